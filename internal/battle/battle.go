@@ -0,0 +1,177 @@
+// Package battle simulates turn-based battles between two Pokemon, with the
+// damage formula of the mainline games
+package battle
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+// CriticalChance is the chance of a move to be a critical hit, 1 in 24
+const CriticalChance = 1.0 / 24
+
+var ErrBattleOver = errors.New("the battle is over")
+
+type Battle struct {
+	Player   *Battler
+	Opponent *Battler
+	Rand     *rand.Rand
+	Chart    TypeChart
+	Turn     int
+}
+
+func New(player, opponent *Battler, r *rand.Rand, chart TypeChart) *Battle {
+	return &Battle{Player: player, Opponent: opponent, Rand: r, Chart: chart}
+}
+
+// Event is a move used during a turn
+type Event struct {
+	Attacker      string
+	Defender      string
+	Move          string
+	Missed        bool
+	Damage        int
+	Effectiveness float64
+	Critical      bool
+	DefenderHP    int // left after the move
+	Fainted       bool
+}
+
+// Over reports whether a battler fainted
+func (b *Battle) Over() bool {
+	return b.Player.Fainted() || b.Opponent.Fainted()
+}
+
+// Winner is the battler left standing, nil while the battle goes on
+func (b *Battle) Winner() *Battler {
+	switch {
+	case b.Opponent.Fainted():
+		return b.Player
+	case b.Player.Fainted():
+		return b.Opponent
+	}
+	return nil
+}
+
+// PlayTurn has the player use move and the opponent the one its AI picks, in
+// order of move priority then speed. The turn ends early if a battler faints.
+func (b *Battle) PlayTurn(move string) ([]Event, error) {
+	if b.Over() {
+		return nil, ErrBattleOver
+	}
+	playerMove, err := b.Player.Move(move)
+	if err != nil {
+		return nil, err
+	}
+	opponentMove, err := b.Opponent.Move(ChooseMove(b.Opponent, b.Player, b.Chart))
+	if err != nil {
+		return nil, err
+	}
+	b.Turn++
+
+	type action struct {
+		attacker, defender *Battler
+		move               *MoveSlot
+	}
+	actions := []action{{b.Player, b.Opponent, playerMove}, {b.Opponent, b.Player, opponentMove}}
+	if b.goesSecond(playerMove, opponentMove) {
+		actions[0], actions[1] = actions[1], actions[0]
+	}
+	var events []Event
+	for _, a := range actions {
+		if a.attacker.Fainted() {
+			break
+		}
+		events = append(events, b.use(a.attacker, a.defender, a.move))
+	}
+	return events, nil
+}
+
+// goesSecond reports whether the opponent moves before the player
+func (b *Battle) goesSecond(player, opponent *MoveSlot) bool {
+	if player.Priority != opponent.Priority {
+		return opponent.Priority > player.Priority
+	}
+	if b.Player.Stats.Speed != b.Opponent.Stats.Speed {
+		return b.Opponent.Stats.Speed > b.Player.Stats.Speed
+	}
+	// speed ties are settled at random
+	return b.Rand.IntN(2) == 0
+}
+
+func (b *Battle) use(attacker, defender *Battler, move *MoveSlot) Event {
+	move.PPLeft--
+	event := Event{Attacker: attacker.Name, Defender: defender.Name, Move: move.Name, Effectiveness: 1}
+	if move.Accuracy > 0 && b.Rand.IntN(100) >= move.Accuracy {
+		event.Missed = true
+	} else if move.Damaging() {
+		event.Damage, event.Effectiveness, event.Critical = Damage(attacker, defender, move.Move, b.Chart, b.Rand)
+		defender.HP = max(defender.HP-event.Damage, 0)
+	}
+	event.DefenderHP = defender.HP
+	event.Fainted = defender.Fainted()
+	return event
+}
+
+// Damage is the damage dealt by move as in the games since generation V:
+//
+//	((2*level/5 + 2) * power * attack/defense / 50 + 2) * modifiers
+//
+// the modifiers being a random factor from 0.85 to 1, 1.5 for a critical
+// hit, 1.5 for a move of the attacker's type (STAB) and the type effectiveness
+func Damage(attacker, defender *Battler, move Move, chart TypeChart, r *rand.Rand) (damage int, effectiveness float64, critical bool) {
+	attack, defense := attacker.Stats.Attack, defender.Stats.Defense
+	if move.Class == "special" {
+		attack, defense = attacker.Stats.SpecialAttack, defender.Stats.SpecialDefense
+	}
+	base := (2*attacker.Level/5+2)*move.Power*attack/max(defense, 1)/50 + 2
+
+	effectiveness = Effectiveness(chart, move.Type, defender.Types)
+	if effectiveness == 0 {
+		return 0, 0, false
+	}
+	modifier := float64(85+r.IntN(16)) / 100 * effectiveness
+	if critical = r.Float64() < CriticalChance; critical {
+		modifier *= 1.5
+	}
+	if stab(attacker, move) {
+		modifier *= 1.5
+	}
+	return max(int(float64(base)*modifier), 1), effectiveness, critical
+}
+
+func stab(attacker *Battler, move Move) bool {
+	for _, t := range attacker.Types {
+		if t == move.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// ChooseMove is the opponent AI: the move expected to deal the most damage,
+// weighing power by STAB, type effectiveness and accuracy
+func ChooseMove(self, foe *Battler, chart TypeChart) string {
+	names := self.MoveNames()
+	best, bestScore := names[0], -1.0
+	for _, name := range names {
+		move, err := self.Move(name)
+		if err != nil {
+			continue
+		}
+		score := 0.0
+		if move.Damaging() {
+			score = float64(move.Power) * Effectiveness(chart, move.Type, foe.Types)
+			if stab(self, move.Move) {
+				score *= 1.5
+			}
+			if move.Accuracy > 0 {
+				score *= float64(move.Accuracy) / 100
+			}
+		}
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,281 @@
+package battle_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/leobel/pokedexcli/internal/battle"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+func pokemon(t *testing.T, data string) pokeapi.Pokemon {
+	t.Helper()
+	var p pokeapi.Pokemon
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// chart keeps the matchups that are not neutral, by attacking then defending type
+type chart map[string]map[string]float64
+
+func (c chart) Effectiveness(attack, defend string) float64 {
+	if m, ok := c[attack][defend]; ok {
+		return m
+	}
+	return 1
+}
+
+// defaultChart is the type chart of the games since generation VI
+var defaultChart battle.TypeChart = chart{
+	"normal":   {"rock": 0.5, "ghost": 0, "steel": 0.5},
+	"fire":     {"fire": 0.5, "water": 0.5, "grass": 2, "ice": 2, "bug": 2, "rock": 0.5, "dragon": 0.5, "steel": 2},
+	"water":    {"fire": 2, "water": 0.5, "grass": 0.5, "ground": 2, "rock": 2, "dragon": 0.5},
+	"electric": {"water": 2, "electric": 0.5, "grass": 0.5, "ground": 0, "flying": 2, "dragon": 0.5},
+	"grass":    {"fire": 0.5, "water": 2, "grass": 0.5, "poison": 0.5, "ground": 2, "flying": 0.5, "bug": 0.5, "rock": 2, "dragon": 0.5, "steel": 0.5},
+	"ice":      {"fire": 0.5, "water": 0.5, "grass": 2, "ice": 0.5, "ground": 2, "flying": 2, "dragon": 2, "steel": 0.5},
+	"fighting": {"normal": 2, "ice": 2, "poison": 0.5, "flying": 0.5, "psychic": 0.5, "bug": 0.5, "rock": 2, "ghost": 0, "dark": 2, "steel": 2, "fairy": 0.5},
+	"poison":   {"grass": 2, "poison": 0.5, "ground": 0.5, "rock": 0.5, "ghost": 0.5, "steel": 0, "fairy": 2},
+	"ground":   {"fire": 2, "electric": 2, "grass": 0.5, "poison": 2, "flying": 0, "bug": 0.5, "rock": 2, "steel": 2},
+	"flying":   {"electric": 0.5, "grass": 2, "fighting": 2, "bug": 2, "rock": 0.5, "steel": 0.5},
+	"psychic":  {"fighting": 2, "poison": 2, "psychic": 0.5, "dark": 0, "steel": 0.5},
+	"bug":      {"fire": 0.5, "grass": 2, "fighting": 0.5, "poison": 0.5, "flying": 0.5, "psychic": 2, "ghost": 0.5, "dark": 2, "steel": 0.5, "fairy": 0.5},
+	"rock":     {"fire": 2, "ice": 2, "fighting": 0.5, "ground": 0.5, "flying": 2, "bug": 2, "steel": 0.5},
+	"ghost":    {"normal": 0, "psychic": 2, "ghost": 2, "dark": 0.5},
+	"dragon":   {"dragon": 2, "steel": 0.5, "fairy": 0},
+	"dark":     {"fighting": 0.5, "psychic": 2, "ghost": 2, "dark": 0.5, "fairy": 0.5},
+	"steel":    {"fire": 0.5, "water": 0.5, "electric": 0.5, "ice": 2, "rock": 2, "steel": 0.5, "fairy": 2},
+	"fairy":    {"fire": 0.5, "fighting": 2, "poison": 0.5, "dragon": 2, "dark": 2, "steel": 0.5},
+}
+
+var (
+	thunderShock = battle.Move{Name: "thunder-shock", Type: "electric", Class: "special", Power: 40, Accuracy: 100, PP: 30}
+	quickAttack  = battle.Move{Name: "quick-attack", Type: "normal", Class: "physical", Power: 40, Accuracy: 100, PP: 30, Priority: 1}
+	growl        = battle.Move{Name: "growl", Type: "normal", Class: "status", Accuracy: 100, PP: 40}
+	waterGun     = battle.Move{Name: "water-gun", Type: "water", Class: "special", Power: 40, Accuracy: 100, PP: 25}
+	tackle       = battle.Move{Name: "tackle", Type: "normal", Class: "physical", Power: 40, Accuracy: 100, PP: 35}
+)
+
+func pikachu(t *testing.T) *battle.Battler {
+	return battle.NewBattler(pokemon(t, `{"name":"pikachu","types":[{"type":{"name":"electric"}}],"stats":[
+		{"base_stat":35,"stat":{"name":"hp"}},{"base_stat":55,"stat":{"name":"attack"}},{"base_stat":40,"stat":{"name":"defense"}},
+		{"base_stat":50,"stat":{"name":"special-attack"}},{"base_stat":50,"stat":{"name":"special-defense"}},{"base_stat":90,"stat":{"name":"speed"}}]}`),
+		50, battle.Traits{}, []battle.Move{thunderShock, quickAttack, growl})
+}
+
+func squirtle(t *testing.T) *battle.Battler {
+	return battle.NewBattler(pokemon(t, `{"name":"squirtle","types":[{"type":{"name":"water"}}],"stats":[
+		{"base_stat":44,"stat":{"name":"hp"}},{"base_stat":48,"stat":{"name":"attack"}},{"base_stat":65,"stat":{"name":"defense"}},
+		{"base_stat":50,"stat":{"name":"special-attack"}},{"base_stat":64,"stat":{"name":"special-defense"}},{"base_stat":43,"stat":{"name":"speed"}}]}`),
+		50, battle.Traits{}, []battle.Move{tackle, waterGun})
+}
+
+func TestNewBattler(t *testing.T) {
+	p := pikachu(t)
+	want := battle.Stats{HP: 95, Attack: 60, Defense: 45, SpecialAttack: 55, SpecialDefense: 55, Speed: 95}
+	if p.Stats != want || p.HP != 95 {
+		t.Errorf("Stats = %+v at %d HP; want %+v at full HP", p.Stats, p.HP, want)
+	}
+	if len(p.Moves) != 3 || p.Moves[0].PPLeft != 30 {
+		t.Errorf("Moves = %+v; want 3 with full PP", p.Moves)
+	}
+}
+
+func TestEffectiveness(t *testing.T) {
+	cases := []struct {
+		attack string
+		defend []string
+		want   float64
+	}{
+		{"electric", []string{"water", "flying"}, 4},
+		{"electric", []string{"water"}, 2},
+		{"electric", []string{"grass", "dragon"}, 0.25},
+		{"electric", []string{"ground", "flying"}, 0},
+		{"normal", []string{"water"}, 1},
+		{"", []string{"ghost"}, 1}, // struggle has no type
+	}
+	for _, c := range cases {
+		if got := battle.Effectiveness(defaultChart, c.attack, c.defend); got != c.want {
+			t.Errorf("Effectiveness(%s, %v) = %v; want %v", c.attack, c.defend, got, c.want)
+		}
+	}
+}
+
+func TestDamage(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	p, s := pikachu(t), squirtle(t)
+	// base damage is (22 * 40 * 55/69) / 50 + 2 = 16, times 2 for the type and 1.5 for STAB
+	for range 100 {
+		damage, effectiveness, critical := battle.Damage(p, s, thunderShock, defaultChart, r)
+		low, high := 40, 48
+		if critical {
+			low, high = 61, 72
+		}
+		if effectiveness != 2 || damage < low || damage > high {
+			t.Fatalf("Damage() = %d (x%v, critical %v); want %d to %d", damage, effectiveness, critical, low, high)
+		}
+	}
+
+	ground := battle.NewBattler(pokemon(t, `{"name":"diglett","types":[{"type":{"name":"ground"}}]}`), 50, battle.Traits{}, nil)
+	if damage, effectiveness, _ := battle.Damage(p, ground, thunderShock, defaultChart, r); damage != 0 || effectiveness != 0 {
+		t.Errorf("Damage() on ground = %d (x%v); want no effect", damage, effectiveness)
+	}
+}
+
+func TestPlayTurnOrder(t *testing.T) {
+	b := battle.New(squirtle(t), pikachu(t), rand.New(rand.NewPCG(1, 2)), defaultChart)
+	events, err := b.PlayTurn("tackle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the faster pikachu moves first, picking its super effective STAB move
+	if len(events) != 2 || events[0].Attacker != "pikachu" || events[0].Move != "thunder-shock" || events[1].Attacker != "squirtle" {
+		t.Fatalf("PlayTurn() = %+v; want pikachu's thunder-shock first", events)
+	}
+	if b.Player.HP != events[0].DefenderHP || b.Opponent.Moves[0].PPLeft != 29 {
+		t.Errorf("HP = %d, PP = %d; want the damage and PP taken", b.Player.HP, b.Opponent.Moves[0].PPLeft)
+	}
+
+	// priority beats speed
+	b = battle.New(pikachu(t), squirtle(t), rand.New(rand.NewPCG(1, 2)), defaultChart)
+	b.Player.Stats.Speed = 1
+	if events, _ := b.PlayTurn("quick-attack"); events[0].Attacker != "pikachu" {
+		t.Errorf("PlayTurn(quick-attack) = %+v; want pikachu first", events)
+	}
+}
+
+func TestPlayTurnUntilFainted(t *testing.T) {
+	b := battle.New(pikachu(t), squirtle(t), rand.New(rand.NewPCG(3, 4)), defaultChart)
+	for !b.Over() {
+		events, err := b.PlayTurn("thunder-shock")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := events[len(events)-1]; b.Over() && !last.Fainted {
+			t.Errorf("last event %+v should report the faint", last)
+		}
+	}
+	if b.Winner() != b.Player {
+		t.Errorf("Winner() = %s; want pikachu", b.Winner().Name)
+	}
+	if _, err := b.PlayTurn("thunder-shock"); !errors.Is(err, battle.ErrBattleOver) {
+		t.Errorf("PlayTurn() after the end = %v; want %v", err, battle.ErrBattleOver)
+	}
+}
+
+func TestMovesAndStruggle(t *testing.T) {
+	p := pikachu(t)
+	if _, err := p.Move("surf"); err == nil {
+		t.Error("Move(surf) should fail, pikachu does not know it")
+	}
+	if _, err := p.Move("struggle"); err == nil {
+		t.Error("Move(struggle) should fail while there is PP left")
+	}
+	for _, slot := range p.Moves {
+		slot.PPLeft = 0
+	}
+	if names := p.MoveNames(); !slices.Equal(names, []string{"struggle"}) {
+		t.Errorf("MoveNames() = %v; want [struggle]", names)
+	}
+	if name := battle.ChooseMove(p, squirtle(t), defaultChart); name != "struggle" {
+		t.Errorf("ChooseMove() = %s; want struggle", name)
+	}
+}
+
+func TestLevelUpMoves(t *testing.T) {
+	p := pokemon(t, `{"name":"pikachu","moves":[
+		{"move":{"name":"thunder-shock"},"version_group_details":[{"level_learned_at":1,"move_learn_method":{"name":"level-up"}}]},
+		{"move":{"name":"thunderbolt"},"version_group_details":[{"level_learned_at":0,"move_learn_method":{"name":"machine"}}]},
+		{"move":{"name":"thunder"},"version_group_details":[{"level_learned_at":58,"move_learn_method":{"name":"level-up"}}]},
+		{"move":{"name":"quick-attack"},"version_group_details":[{"level_learned_at":6,"move_learn_method":{"name":"level-up"}}]},
+		{"move":{"name":"growl"},"version_group_details":[{"level_learned_at":1,"move_learn_method":{"name":"level-up"}}]},
+		{"move":{"name":"spark"},"version_group_details":[{"level_learned_at":20,"move_learn_method":{"name":"level-up"}}]},
+		{"move":{"name":"slam"},"version_group_details":[{"level_learned_at":30,"move_learn_method":{"name":"level-up"}}]}]}`)
+	want := []string{"growl", "quick-attack", "spark", "slam"}
+	if got := battle.LevelUpMoves(p, 50); !slices.Equal(got, want) {
+		t.Errorf("LevelUpMoves() = %v; want %v", got, want)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	types := make([]pokeapi.Type, 0, len(battle.Types))
+	for _, name := range battle.Types {
+		var typ pokeapi.Type
+		typ.Name = name
+		types = append(types, typ)
+	}
+	if _, err := battle.NewMatrix(types[1:]); err == nil {
+		t.Error("NewMatrix() without normal should fail")
+	}
+
+	electric := &types[slices.Index(battle.Types, "electric")]
+	json.Unmarshal([]byte(`{"name":"electric","damage_relations":{
+		"double_damage_to":[{"name":"water"},{"name":"flying"}],
+		"half_damage_to":[{"name":"electric"},{"name":"grass"},{"name":"dragon"}],
+		"no_damage_to":[{"name":"ground"}, {"name":"stellar"}]}}`), electric)
+	m, err := battle.NewMatrix(types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the API agrees with the chart of the games
+	for _, defend := range battle.Types {
+		if got, want := m.Effectiveness("electric", defend), defaultChart.Effectiveness("electric", defend); got != want {
+			t.Errorf("Effectiveness(electric, %s) = %v; want %v", defend, got, want)
+		}
+	}
+	if got := m.Effectiveness("", "ghost"); got != 1 {
+		t.Errorf("Effectiveness of a typeless move = %v; want 1", got)
+	}
+}
+
+func TestTraitsStats(t *testing.T) {
+	p := pokemon(t, `{"name":"pikachu","stats":[
+		{"base_stat":35,"stat":{"name":"hp"}},{"base_stat":55,"stat":{"name":"attack"}},{"base_stat":40,"stat":{"name":"defense"}},
+		{"base_stat":50,"stat":{"name":"special-attack"}},{"base_stat":50,"stat":{"name":"special-defense"}},{"base_stat":90,"stat":{"name":"speed"}}]}`)
+	traits := battle.Traits{
+		IVs:    map[string]int{"hp": 31, "attack": 20, "defense": 0, "special-attack": 31, "special-defense": 10, "speed": 31},
+		EVs:    map[string]int{"attack": 252, "speed": 252},
+		Nature: battle.Nature{Name: "adamant", Increased: "attack", Decreased: "special-attack"},
+	}
+	// attack is ((110 + 20 + 63) * 50/100 + 5) * 1.1, special attack (131 * 50/100 + 5) * 0.9
+	want := battle.Stats{HP: 110, Attack: 111, Defense: 45, SpecialAttack: 63, SpecialDefense: 60, Speed: 142}
+	if got := traits.Stats(p, 50); got != want {
+		t.Errorf("Stats() = %+v; want %+v", got, want)
+	}
+	if got := (battle.Traits{}).Stats(p, 50); got != (battle.Stats{HP: 95, Attack: 60, Defense: 45, SpecialAttack: 55, SpecialDefense: 55, Speed: 95}) {
+		t.Errorf("Stats() without traits = %+v; want the base stats alone", got)
+	}
+}
+
+func TestRollTraits(t *testing.T) {
+	traits := battle.RollTraits(rand.New(rand.NewPCG(1, 2)), battle.Nature{Name: "hardy"})
+	if len(traits.IVs) != len(battle.StatNames) || len(traits.EVs) != 0 || traits.Nature.Name != "hardy" {
+		t.Fatalf("RollTraits() = %+v; want an IV for every stat and no EVs", traits)
+	}
+	for name, iv := range traits.IVs {
+		if iv < 0 || iv > battle.MaxIV {
+			t.Errorf("IV %s = %d; want 0 to %d", name, iv, battle.MaxIV)
+		}
+	}
+}
+
+func TestGainEffort(t *testing.T) {
+	geodude := pokemon(t, `{"name":"geodude","stats":[{"base_stat":100,"effort":1,"stat":{"name":"defense"}}]}`)
+	onix := pokemon(t, `{"name":"onix","stats":[{"base_stat":160,"effort":1,"stat":{"name":"defense"}},{"base_stat":70,"effort":2,"stat":{"name":"speed"}}]}`)
+	var traits battle.Traits
+	traits.GainEffort(geodude)
+	if traits.EVs["defense"] != 1 {
+		t.Errorf("EVs = %v; want 1 defense", traits.EVs)
+	}
+
+	// capped at 252 a stat and 510 in all
+	traits.EVs = map[string]int{"defense": 252, "attack": 252, "hp": 5}
+	traits.GainEffort(onix)
+	if traits.EVs["defense"] != 252 || traits.EVs["speed"] != 1 {
+		t.Errorf("EVs = %v; want defense to stay at 252 and speed to reach the 510 total", traits.EVs)
+	}
+}
@@ -0,0 +1,162 @@
+package battle
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+// DefaultLevel is the level Pokemon battle at
+const DefaultLevel = 50
+
+// MaxMoves is how many moves a Pokemon knows at once
+const MaxMoves = 4
+
+// Move is what a Pokemon can do on its turn. Accuracy is 0 for moves that
+// never miss, Class is physical, special or status.
+type Move struct {
+	Name     string
+	Type     string
+	Class    string
+	Power    int
+	Accuracy int
+	PP       int
+	Priority int
+}
+
+// Struggle is used when a Pokemon has no PP left in any of its moves
+var Struggle = Move{Name: "struggle", Class: "physical", Power: 50}
+
+func NewMove(move pokeapi.Move) Move {
+	m := Move{
+		Name:     move.Name,
+		Type:     move.Type.Name,
+		Class:    move.DamageClass.Name,
+		PP:       move.PP,
+		Priority: move.Priority,
+	}
+	if move.Power != nil {
+		m.Power = *move.Power
+	}
+	if move.Accuracy != nil {
+		m.Accuracy = *move.Accuracy
+	}
+	return m
+}
+
+// Damaging reports whether the move deals direct damage, status moves have no
+// effect in battles yet
+func (m Move) Damaging() bool {
+	return m.Class != "status" && m.Power > 0
+}
+
+// MoveSlot is a known move and the PP left for it
+type MoveSlot struct {
+	Move
+	PPLeft int
+}
+
+type Stats struct {
+	HP             int
+	Attack         int
+	Defense        int
+	SpecialAttack  int
+	SpecialDefense int
+	Speed          int
+}
+
+// Battler is a Pokemon in a battle
+type Battler struct {
+	Name  string
+	Level int
+	Types []string
+	Stats Stats
+	HP    int
+	Moves []*MoveSlot
+}
+
+// NewBattler gets a Pokemon ready to battle at level, at full HP and PP
+func NewBattler(pokemon pokeapi.Pokemon, level int, traits Traits, moves []Move) *Battler {
+	b := &Battler{Name: pokemon.Name, Level: level, Stats: traits.Stats(pokemon, level)}
+	for _, t := range pokemon.Types {
+		b.Types = append(b.Types, t.Type.Name)
+	}
+	b.HP = b.Stats.HP
+	for _, move := range moves {
+		b.Moves = append(b.Moves, &MoveSlot{move, move.PP})
+	}
+	return b
+}
+
+func (b *Battler) Fainted() bool {
+	return b.HP <= 0
+}
+
+// Move returns the move called name, struggle being only allowed when there
+// is no PP left at all
+func (b *Battler) Move(name string) (*MoveSlot, error) {
+	if name == Struggle.Name && b.outOfPP() {
+		return &MoveSlot{Struggle, 1}, nil
+	}
+	for _, slot := range b.Moves {
+		if slot.Name != name {
+			continue
+		}
+		if slot.PPLeft <= 0 {
+			return nil, fmt.Errorf("%s has no PP left for %s", b.Name, name)
+		}
+		return slot, nil
+	}
+	return nil, fmt.Errorf("%s does not know %s", b.Name, name)
+}
+
+// MoveNames lists what the battler can use, struggle when out of PP
+func (b *Battler) MoveNames() []string {
+	if b.outOfPP() {
+		return []string{Struggle.Name}
+	}
+	var names []string
+	for _, slot := range b.Moves {
+		if slot.PPLeft > 0 {
+			names = append(names, slot.Name)
+		}
+	}
+	return names
+}
+
+func (b *Battler) outOfPP() bool {
+	for _, slot := range b.Moves {
+		if slot.PPLeft > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// LevelUpMoves returns the names of the last moves pokemon learns by levelling
+// up to level, like a wild Pokemon met at that level knows them
+func LevelUpMoves(pokemon pokeapi.Pokemon, level int) []string {
+	type learnt struct {
+		name  string
+		level int
+	}
+	var moves []learnt
+	for _, move := range pokemon.Moves {
+		if n := len(move.VersionGroupDetails); n > 0 {
+			// details go from the oldest to the latest games
+			detail := move.VersionGroupDetails[n-1]
+			if detail.MoveLearnMethod.Name == "level-up" && detail.LevelLearnedAt <= level {
+				moves = append(moves, learnt{move.Move.Name, detail.LevelLearnedAt})
+			}
+		}
+	}
+	slices.SortStableFunc(moves, func(a, b learnt) int { return cmp.Compare(a.level, b.level) })
+	moves = moves[max(len(moves)-MaxMoves, 0):]
+	names := make([]string, 0, len(moves))
+	for _, move := range moves {
+		names = append(names, move.name)
+	}
+	return names
+}
@@ -0,0 +1,113 @@
+package battle
+
+import (
+	"math/rand/v2"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+const (
+	MaxIV      = 31
+	MaxEV      = 252 // per stat
+	MaxTotalEV = 510
+)
+
+// StatNames are the stats of every Pokemon, in the API order
+var StatNames = []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+
+// Nature raises the Increased stat by 10% and lowers the Decreased one by as
+// much, a neutral nature leaving both empty
+type Nature struct {
+	Name      string
+	Increased string
+	Decreased string
+}
+
+func NewNature(nature pokeapi.Nature) Nature {
+	n := Nature{Name: nature.Name}
+	if nature.IncreasedStat != nil && nature.DecreasedStat != nil && nature.IncreasedStat.Name != nature.DecreasedStat.Name {
+		n.Increased = nature.IncreasedStat.Name
+		n.Decreased = nature.DecreasedStat.Name
+	}
+	return n
+}
+
+// Traits tell apart Pokemon of the same species: their individual values,
+// rolled when met, the effort values they gain by defeating others and their
+// nature. The zero Traits has none of them, as wild opponents.
+type Traits struct {
+	IVs    map[string]int
+	EVs    map[string]int
+	Nature Nature
+}
+
+// RollTraits rolls the individual values of a Pokemon met with nature, it
+// has no effort values yet
+func RollTraits(r *rand.Rand, nature Nature) Traits {
+	t := Traits{IVs: map[string]int{}, EVs: map[string]int{}, Nature: nature}
+	for _, name := range StatNames {
+		t.IVs[name] = r.IntN(MaxIV + 1)
+	}
+	return t
+}
+
+// Stat computes a stat at level as in the games from generation III on:
+// (2 base + IV + EV/4) level/100, plus level + 10 for hp and 5 for the
+// others then changed by the nature.
+func (t Traits) Stat(name string, base, level int) int {
+	value := (2*base + t.IVs[name] + t.EVs[name]/4) * level / 100
+	if name == "hp" {
+		return value + level + 10
+	}
+	value += 5
+	switch name {
+	case t.Nature.Increased:
+		value = value * 110 / 100
+	case t.Nature.Decreased:
+		value = value * 90 / 100
+	}
+	return value
+}
+
+// Stats computes every stat of pokemon at level
+func (t Traits) Stats(pokemon pokeapi.Pokemon, level int) Stats {
+	var stats Stats
+	for _, stat := range pokemon.Stats {
+		value := t.Stat(stat.Stat.Name, stat.BaseStat, level)
+		switch stat.Stat.Name {
+		case "hp":
+			stats.HP = value
+		case "attack":
+			stats.Attack = value
+		case "defense":
+			stats.Defense = value
+		case "special-attack":
+			stats.SpecialAttack = value
+		case "special-defense":
+			stats.SpecialDefense = value
+		case "speed":
+			stats.Speed = value
+		}
+	}
+	return stats
+}
+
+// GainEffort adds the effort yields of a defeated Pokemon to the EVs, up to
+// MaxEV a stat and MaxTotalEV in all
+func (t *Traits) GainEffort(defeated pokeapi.Pokemon) {
+	if t.EVs == nil {
+		t.EVs = map[string]int{}
+	}
+	total := 0
+	for _, ev := range t.EVs {
+		total += ev
+	}
+	for _, stat := range defeated.Stats {
+		gain := min(stat.Effort, MaxEV-t.EVs[stat.Stat.Name], MaxTotalEV-total)
+		if gain <= 0 {
+			continue
+		}
+		t.EVs[stat.Stat.Name] += gain
+		total += gain
+	}
+}
@@ -0,0 +1,82 @@
+package battle
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+// Types are the 18 types of the games, in the order of the API ids
+var Types = []string{
+	"normal", "fighting", "flying", "poison", "ground", "rock", "bug", "ghost", "steel",
+	"fire", "water", "grass", "electric", "psychic", "ice", "dragon", "dark", "fairy",
+}
+
+// TypeChart tells how effective a move of an attacking type is against a
+// defending type: 2 super effective, 0.5 not very effective, 0 no effect
+type TypeChart interface {
+	Effectiveness(attack, defend string) float64
+}
+
+// Effectiveness multiplies the effectiveness of a move of type attack against
+// every type of the defender, i.e: 4 for a double weakness
+func Effectiveness(c TypeChart, attack string, defend []string) float64 {
+	m := 1.0
+	for _, t := range defend {
+		m *= c.Effectiveness(attack, t)
+	}
+	return m
+}
+
+// Matrix is a type chart built from the API, Matrix[attack][defend] being the
+// effectiveness with types indexed as in Types
+type Matrix [18][18]float64
+
+// NewMatrix builds the chart from the damage relations of every type in Types.
+// Relations with types outside of Types, i.e: stellar, are left out.
+func NewMatrix(types []pokeapi.Type) (*Matrix, error) {
+	var m Matrix
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] = 1
+		}
+	}
+	seen := make([]bool, len(Types))
+	for _, t := range types {
+		attack := slices.Index(Types, t.Name)
+		if attack < 0 {
+			continue
+		}
+		seen[attack] = true
+		relations := t.DamageRelations
+		for _, r := range relations.DoubleDamageTo {
+			m.set(attack, r.Name, 2)
+		}
+		for _, r := range relations.HalfDamageTo {
+			m.set(attack, r.Name, 0.5)
+		}
+		for _, r := range relations.NoDamageTo {
+			m.set(attack, r.Name, 0)
+		}
+	}
+	if i := slices.Index(seen, false); i >= 0 {
+		return nil, fmt.Errorf("no damage relations for type %s", Types[i])
+	}
+	return &m, nil
+}
+
+func (m *Matrix) set(attack int, defend string, effectiveness float64) {
+	if j := slices.Index(Types, defend); j >= 0 {
+		m[attack][j] = effectiveness
+	}
+}
+
+// Effectiveness is neutral for types the matrix does not know, i.e: struggle's
+func (m *Matrix) Effectiveness(attack, defend string) float64 {
+	i, j := slices.Index(Types, attack), slices.Index(Types, defend)
+	if i < 0 || j < 0 {
+		return 1
+	}
+	return m[i][j]
+}
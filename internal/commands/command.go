@@ -1,5 +1,7 @@
 package commands
 
+import "context"
+
 type Command interface {
-	callback(...string) error
+	callback(context.Context, ...string) error
 }
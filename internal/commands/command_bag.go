@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/leobel/pokedexcli/internal/inventory"
+)
+
+// ShowBag lists the items the player carries
+func (c *CommandPokedex[T]) ShowBag(context.Context, ...string) error {
+	if c.Out.JSON() {
+		for _, name := range c.Bag.Names() {
+			if err := c.Out.emit(ItemResult{name, c.Bag[name]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fmt.Fprintln(c.Out, "Your bag:")
+	for _, name := range c.Bag.Names() {
+		// the bag only holds known items
+		item, _ := inventory.Lookup(name)
+		fmt.Fprintf(c.Out, " - %s x%d: %s\n", name, c.Bag[name], item.Description)
+	}
+	if c.Berry != nil {
+		fmt.Fprintf(c.Out, "A %s is ready for the next throw\n", c.Berry.Name)
+	}
+	return nil
+}
+
+// UseItem uses an item of the bag: use <item>. Balls are thrown with catch.
+func (c *CommandPokedex[T]) UseItem(_ context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no item to use")
+	}
+	name := params[0]
+	item, err := inventory.Lookup(name)
+	if err != nil {
+		match, _ := closestMatch(name, inventory.Names())
+		return notFound(fmt.Sprintf("there is no item named %s", name), match)
+	}
+	if item.Kind == inventory.Ball {
+		return fmt.Errorf("balls are thrown with: catch <pokemon> %s", name)
+	}
+	if c.Berry != nil {
+		return fmt.Errorf("a %s is already waiting for the next throw", c.Berry.Name)
+	}
+	if _, err := c.Bag.Take(name); err != nil {
+		return err
+	}
+	c.Berry = &item
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "You get a %s ready, the next Pokemon you throw a ball at will be easier to catch\n", name)
+	}
+	return nil
+}
+
+// BallNames lists the balls in the bag, used for tab completion
+func (c *CommandPokedex[T]) BallNames() []string {
+	var balls []string
+	for _, name := range c.Bag.Names() {
+		if item, _ := inventory.Lookup(name); item.Kind == inventory.Ball {
+			balls = append(balls, name)
+		}
+	}
+	return balls
+}
+
+// ItemNames lists the items in the bag, used for tab completion
+func (c *CommandPokedex[T]) ItemNames() []string {
+	return c.Bag.Names()
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/leobel/pokedexcli/internal/battle"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+// CommandBattle runs a battle between a caught Pokemon and a wild one of any
+// species, one turn per attack command
+type CommandBattle[T pokecache.Cache] struct {
+	Api     pokeapi.Api[T]
+	Pokedex *CommandPokedex[T]
+	Rand    *rand.Rand
+	Chart   func(context.Context) (battle.TypeChart, error)
+	Battle  *battle.Battle  // nil when not battling
+	Mine    int             // UID of the player's Pokemon in the battle
+	Foe     pokeapi.Pokemon // the opponent, its experience and effort go to the winner
+	Out     *Output
+}
+
+// BattlerResult is a Pokemon taking part in a battle
+type BattlerResult struct {
+	Name  string   `json:"name"`
+	Level int      `json:"level"`
+	HP    int      `json:"hp"`
+	MaxHP int      `json:"max_hp"`
+	Types []string `json:"types"`
+	Moves []string `json:"moves"`
+}
+
+// BattleResult is printed by battle when it starts
+type BattleResult struct {
+	Player   BattlerResult `json:"player"`
+	Opponent BattlerResult `json:"opponent"`
+}
+
+// TurnResult is printed by attack for every move used during the turn
+type TurnResult struct {
+	Turn          int     `json:"turn"`
+	Attacker      string  `json:"attacker"`
+	Defender      string  `json:"defender"`
+	Move          string  `json:"move"`
+	Missed        bool    `json:"missed"`
+	Damage        int     `json:"damage"`
+	Effectiveness float64 `json:"effectiveness"`
+	Critical      bool    `json:"critical"`
+	DefenderHP    int     `json:"defender_hp"`
+	Fainted       bool    `json:"fainted"`
+}
+
+func NewCommandBattle[T pokecache.Cache](api pokeapi.Api[T], pokedex *CommandPokedex[T], chart func(context.Context) (battle.TypeChart, error), r *rand.Rand, out *Output) *CommandBattle[T] {
+	return &CommandBattle[T]{Api: api, Pokedex: pokedex, Chart: chart, Rand: r, Out: out}
+}
+
+var errNoBattle = errors.New("you are not in a battle, start one with `battle <mine> <opponent>`")
+
+// Start begins a battle: battle [mine] <opponent>, mine being a party member
+// and the lead by default, the opponent a wild Pokemon of any species. The opponent battles at the level of the player's
+// Pokemon, without IVs, EVs or nature, both knowing the last moves they
+// learned by then.
+func (c *CommandBattle[T]) Start(ctx context.Context, params ...string) error {
+	if len(params) == 0 || len(params) > 2 {
+		return errors.New("invalid: battle [mine] <opponent>")
+	}
+	if c.Battle != nil {
+		return fmt.Errorf("you are already battling %s, use `flee` to leave", c.Battle.Opponent.Name)
+	}
+	mine, ok := c.Pokedex.Lead()
+	if len(params) == 2 {
+		pokemon, err := c.Pokedex.FindPokemon(params[0])
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(c.Pokedex.Party, pokemon.UID) {
+			return fmt.Errorf("%s is in the box, use `party add` first", pokemon.Label())
+		}
+		mine, ok = *pokemon, true
+	}
+	if !ok {
+		return errors.New("your party is empty, catch a Pokemon or use `party add` first")
+	}
+	name := params[len(params)-1]
+	opponent, err := c.Api.GetPokemon(ctx, name)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return suggestNotFound[T](ctx, c.Api, "Pokemon", "pokemon", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	level := mine.Level
+	if level == 0 {
+		level = battle.DefaultLevel
+	}
+	player, err := c.newBattler(ctx, mine.Pokemon, level, mine.Traits)
+	if err != nil {
+		return err
+	}
+	foe, err := c.newBattler(ctx, *opponent, level, battle.Traits{})
+	if err != nil {
+		return err
+	}
+	chart, err := c.Chart(ctx)
+	if err != nil {
+		return err
+	}
+	c.Battle = battle.New(player, foe, c.Rand, chart)
+	c.Mine = mine.UID
+	c.Foe = *opponent
+	if c.Out.JSON() {
+		return c.Out.emit(BattleResult{newBattlerResult(player), newBattlerResult(foe)})
+	}
+	fmt.Fprintf(c.Out, "%s vs %s\n", describeBattler(player), describeBattler(foe))
+	c.printMoves()
+	return nil
+}
+
+func (c *CommandBattle[T]) newBattler(ctx context.Context, pokemon pokeapi.Pokemon, level int, traits battle.Traits) (*battle.Battler, error) {
+	var moves []battle.Move
+	for _, name := range battle.LevelUpMoves(pokemon, level) {
+		move, err := c.Api.GetMove(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("move %s: %w", name, err)
+		}
+		moves = append(moves, battle.NewMove(*move))
+	}
+	return battle.NewBattler(pokemon, level, traits, moves), nil
+}
+
+// Attack plays a turn of the battle with the player's move: attack <move>.
+// Winning gives the player's Pokemon experience and effort values.
+func (c *CommandBattle[T]) Attack(ctx context.Context, params ...string) error {
+	if c.Battle == nil {
+		return errNoBattle
+	}
+	if len(params) == 0 {
+		return fmt.Errorf("invalid: attack <move>, one of %s", strings.Join(c.MoveNames(), ", "))
+	}
+	events, err := c.Battle.PlayTurn(params[0])
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if c.Out.JSON() {
+			result := TurnResult{c.Battle.Turn, event.Attacker, event.Defender, event.Move, event.Missed,
+				event.Damage, event.Effectiveness, event.Critical, event.DefenderHP, event.Fainted}
+			if err := c.Out.emit(result); err != nil {
+				return err
+			}
+			continue
+		}
+		c.printEvent(event)
+	}
+	if winner := c.Battle.Winner(); winner != nil {
+		if !c.Out.JSON() {
+			fmt.Fprintf(c.Out, "%s wins the battle!\n", winner.Name)
+		}
+		won, level := winner == c.Battle.Player, c.Battle.Opponent.Level
+		c.Battle = nil
+		if _, ok := c.Pokedex.Pokemons[c.Mine]; won && ok {
+			c.Pokedex.GainEffort(c.Mine, c.Foe)
+			return c.Pokedex.GainExp(ctx, c.Mine, ExpYield(c.Foe.BaseExperience, level))
+		}
+		return nil
+	}
+	if !c.Out.JSON() {
+		c.printMoves()
+	}
+	return nil
+}
+
+// Flee ends the battle without a winner
+func (c *CommandBattle[T]) Flee(context.Context, ...string) error {
+	if c.Battle == nil {
+		return errNoBattle
+	}
+	c.Battle = nil
+	if !c.Out.JSON() {
+		fmt.Fprintln(c.Out, "You got away safely")
+	}
+	return nil
+}
+
+// End stops the battle in progress, if any, i.e: once another Pokedex is
+// loaded and the player's Pokemon may be gone
+func (c *CommandBattle[T]) End() {
+	if c.Battle == nil {
+		return
+	}
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "The battle against %s is over\n", c.Battle.Opponent.Name)
+	}
+	c.Battle = nil
+}
+
+// MoveNames lists the moves the player can use, used for tab completion
+func (c *CommandBattle[T]) MoveNames() []string {
+	if c.Battle == nil {
+		return nil
+	}
+	return c.Battle.Player.MoveNames()
+}
+
+func (c *CommandBattle[T]) printEvent(event battle.Event) {
+	fmt.Fprintf(c.Out, "%s used %s!\n", event.Attacker, event.Move)
+	switch {
+	case event.Missed:
+		fmt.Fprintln(c.Out, "  It missed!")
+		return
+	case event.Effectiveness == 0:
+		fmt.Fprintf(c.Out, "  It doesn't affect %s...\n", event.Defender)
+		return
+	case event.Damage == 0:
+		fmt.Fprintln(c.Out, "  But nothing happened!")
+		return
+	case event.Effectiveness > 1:
+		fmt.Fprintln(c.Out, "  It's super effective!")
+	case event.Effectiveness < 1:
+		fmt.Fprintln(c.Out, "  It's not very effective...")
+	}
+	if event.Critical {
+		fmt.Fprintln(c.Out, "  A critical hit!")
+	}
+	fmt.Fprintf(c.Out, "  %s lost %d HP, %d left\n", event.Defender, event.Damage, event.DefenderHP)
+	if event.Fainted {
+		fmt.Fprintf(c.Out, "  %s fainted!\n", event.Defender)
+	}
+}
+
+func (c *CommandBattle[T]) printMoves() {
+	moves := make([]string, 0, len(c.Battle.Player.Moves))
+	for _, slot := range c.Battle.Player.Moves {
+		moves = append(moves, fmt.Sprintf("%s (%s, PP %d/%d)", slot.Name, slot.Type, slot.PPLeft, slot.PP))
+	}
+	if len(moves) == 0 {
+		moves = append(moves, battle.Struggle.Name)
+	}
+	fmt.Fprintf(c.Out, "Pick a move with `attack <move>`: %s\n", strings.Join(moves, ", "))
+}
+
+func describeBattler(b *battle.Battler) string {
+	return fmt.Sprintf("%s (level %d, %s, HP %d/%d)", b.Name, b.Level, strings.Join(b.Types, "/"), b.HP, b.Stats.HP)
+}
+
+func newBattlerResult(b *battle.Battler) BattlerResult {
+	moves := make([]string, 0, len(b.Moves))
+	for _, slot := range b.Moves {
+		moves = append(moves, slot.Name)
+	}
+	return BattlerResult{b.Name, b.Level, b.HP, b.Stats.HP, b.Types, moves}
+}
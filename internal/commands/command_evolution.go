@@ -0,0 +1,130 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+type CommandEvolution[T pokecache.Cache] struct {
+	Api pokeapi.Api[T]
+	Out *Output
+}
+
+func NewCommandEvolution[T pokecache.Cache](api pokeapi.Api[T], out *Output) *CommandEvolution[T] {
+	return &CommandEvolution[T]{api, out}
+}
+
+func (c *CommandEvolution[T]) ShowEvolution(ctx context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no pokemon to look up")
+	}
+	name := params[0]
+	species, err := c.Api.GetPokemonSpecies(ctx, name)
+	if err != nil {
+		return err
+	}
+	id, err := resourceID(species.EvolutionChain.URL)
+	if err != nil {
+		return err
+	}
+	chain, err := c.Api.GetEvolutionChain(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c.Out.JSON() {
+		return c.Out.emit(newEvolutionChainResult(chain.Chain))
+	}
+	fmt.Fprintf(c.Out, "Evolution chain for %s:\n", species.Name)
+	fmt.Fprintln(c.Out, chain.Chain.Species.Name)
+	printEvolutions(c.Out, chain.Chain, 1)
+	return nil
+}
+
+func newEvolutionChainResult(link pokeapi.ChainLink) EvolutionChainResult {
+	result := EvolutionChainResult{Species: link.Species.Name, EvolvesTo: []EvolutionChainResult{}}
+	for _, detail := range link.EvolutionDetails {
+		result.Conditions = append(result.Conditions, describeEvolution(detail))
+	}
+	for _, next := range link.EvolvesTo {
+		result.EvolvesTo = append(result.EvolvesTo, newEvolutionChainResult(next))
+	}
+	return result
+}
+
+// printEvolutions walks every branch below link, indenting each stage
+func printEvolutions(w io.Writer, link pokeapi.ChainLink, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, next := range link.EvolvesTo {
+		conditions := make([]string, 0, len(next.EvolutionDetails))
+		for _, detail := range next.EvolutionDetails {
+			conditions = append(conditions, describeEvolution(detail))
+		}
+		if len(conditions) > 0 {
+			fmt.Fprintf(w, "%s-> %s: %s\n", indent, next.Species.Name, strings.Join(conditions, " or "))
+		} else {
+			fmt.Fprintf(w, "%s-> %s\n", indent, next.Species.Name)
+		}
+		printEvolutions(w, next, depth+1)
+	}
+}
+
+func describeEvolution(detail pokeapi.EvolutionDetail) string {
+	var reqs []string
+	if detail.MinLevel != nil {
+		reqs = append(reqs, fmt.Sprintf("min level %d", *detail.MinLevel))
+	}
+	if detail.Item != nil {
+		reqs = append(reqs, fmt.Sprintf("item %s", detail.Item.Name))
+	}
+	if detail.HeldItem != nil {
+		reqs = append(reqs, fmt.Sprintf("holding %s", detail.HeldItem.Name))
+	}
+	if detail.TradeSpecies != nil {
+		reqs = append(reqs, fmt.Sprintf("for %s", detail.TradeSpecies.Name))
+	}
+	if detail.MinHappiness != nil {
+		reqs = append(reqs, fmt.Sprintf("friendship %d", *detail.MinHappiness))
+	}
+	if detail.MinAffection != nil {
+		reqs = append(reqs, fmt.Sprintf("affection %d", *detail.MinAffection))
+	}
+	if detail.MinBeauty != nil {
+		reqs = append(reqs, fmt.Sprintf("beauty %d", *detail.MinBeauty))
+	}
+	if detail.KnownMove != nil {
+		reqs = append(reqs, fmt.Sprintf("knows %s", detail.KnownMove.Name))
+	}
+	if detail.KnownMoveType != nil {
+		reqs = append(reqs, fmt.Sprintf("knows a %s move", detail.KnownMoveType.Name))
+	}
+	if detail.Location != nil {
+		reqs = append(reqs, fmt.Sprintf("at %s", detail.Location.Name))
+	}
+	if detail.TimeOfDay != "" {
+		reqs = append(reqs, fmt.Sprintf("during %s", detail.TimeOfDay))
+	}
+	if detail.NeedsOverworldRain {
+		reqs = append(reqs, "while raining")
+	}
+	if detail.TurnUpsideDown {
+		reqs = append(reqs, "device upside down")
+	}
+	if len(reqs) == 0 {
+		return detail.Trigger.Name
+	}
+	return fmt.Sprintf("%s (%s)", detail.Trigger.Name, strings.Join(reqs, ", "))
+}
+
+// resourceID extracts the trailing numeric id from a pokeapi resource url,
+// e.g: https://pokeapi.co/api/v2/evolution-chain/10/ -> 10
+func resourceID(rawUrl string) (int, error) {
+	return strconv.Atoi(path.Base(strings.TrimSuffix(rawUrl, "/")))
+}
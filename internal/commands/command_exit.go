@@ -1,20 +1,33 @@
 package commands
 
 import (
-	"errors"
+	"context"
+	"fmt"
 
 	"github.com/leobel/pokedexcli/internal/pokecache"
+	"github.com/leobel/pokedexcli/internal/repl"
 )
 
 type CommandExit[T pokecache.Cache] struct {
 	Cache pokecache.Cache
+	Out   *Output
+	// run before closing, i.e: autosave
+	OnExit []func() error
 }
 
-func NewCommandExit[T pokecache.Cache](cache T) *CommandExit[T] {
-	return &CommandExit[T]{cache}
+func NewCommandExit[T pokecache.Cache](cache T, out *Output, onExit ...func() error) *CommandExit[T] {
+	return &CommandExit[T]{cache, out, onExit}
 }
 
-func (c *CommandExit[T]) Exit(...string) error {
+func (c *CommandExit[T]) Exit(context.Context, ...string) error {
+	for _, hook := range c.OnExit {
+		if err := hook(); err != nil {
+			c.Out.PrintError(err)
+		}
+	}
 	c.Cache.Stop()
-	return errors.New("Closing the Pokedex... Goodbye!")
+	if !c.Out.JSON() {
+		fmt.Fprintln(c.Out, "Closing the Pokedex... Goodbye!")
+	}
+	return repl.ErrExit
 }
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+)
+
+type CommandFormat struct {
+	Out *Output
+}
+
+func NewCommandFormat(out *Output) *CommandFormat {
+	return &CommandFormat{out}
+}
+
+// SetFormat switches the output format, without arguments it shows the current one
+func (c *CommandFormat) SetFormat(_ context.Context, params ...string) error {
+	if len(params) == 0 {
+		if c.Out.JSON() {
+			return c.Out.emit(FormatResult{c.Out.Format})
+		}
+		fmt.Fprintf(c.Out, "output format: %s\n", c.Out.Format)
+		return nil
+	}
+	format, err := ParseFormat(params[0])
+	if err != nil {
+		return err
+	}
+	c.Out.Format = format
+	return nil
+}
+
+// Formats lists the supported formats, used for tab completion
+func (c *CommandFormat) Formats() []string {
+	return []string{string(FormatText), string(FormatJSON)}
+}
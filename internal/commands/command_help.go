@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"maps"
 	"slices"
@@ -10,22 +11,35 @@ import (
 
 type CommandHelp struct {
 	commands *map[string]repl.CliCommand
+	Out      *Output
 }
 
-func NewCommandHelp(commands *map[string]repl.CliCommand) *CommandHelp {
-	return &CommandHelp{commands}
+func NewCommandHelp(commands *map[string]repl.CliCommand, out *Output) *CommandHelp {
+	return &CommandHelp{commands, out}
 }
 
-func (c *CommandHelp) Help(...string) error {
-	fmt.Println("Welcome to the Pokedex!")
-	fmt.Println("Usage:")
-	fmt.Println("")
+func (c *CommandHelp) Help(context.Context, ...string) error {
 	cmds := *c.commands
 	keys := slices.Collect(maps.Keys(cmds))
 	slices.Sort(keys)
+	if c.Out.JSON() {
+		for _, key := range keys {
+			if err := c.Out.emit(CommandResult{key, cmds[key].Description}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fmt.Fprintln(c.Out, "Welcome to the Pokedex!")
+	fmt.Fprintln(c.Out, "Usage:")
+	fmt.Fprintln(c.Out, "")
 	for _, key := range keys {
-		fmt.Printf("%s: %s\n", key, cmds[key].Description)
+		fmt.Fprintf(c.Out, "%s: %s\n", key, cmds[key].Description)
 	}
-	fmt.Println("Up/Down keys: Use it to navigate between previous and next commands")
+	fmt.Fprintln(c.Out, "Up/Down keys: Use it to navigate between previous and next commands")
+	fmt.Fprintln(c.Out, "Ctrl+R: Search the history backwards, press it again for older matches and Esc to cancel")
+	fmt.Fprintln(c.Out, "Left/Right, Home/End, Ctrl+A/E/K/U/W, Alt+B/F: Move the cursor and edit the current line")
+	fmt.Fprintln(c.Out, "Tab: Complete commands, areas and Pokemon names, press it twice to list the options")
+	fmt.Fprintln(c.Out, "Ctrl+C: Cancel the running command and go back to the prompt")
 	return nil
 }
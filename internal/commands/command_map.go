@@ -1,9 +1,12 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/url"
+	"slices"
 	"strconv"
 
 	"github.com/leobel/pokedexcli/internal/pokeapi"
@@ -14,57 +17,77 @@ type CommandMap[T pokecache.Cache] struct {
 	Previous *string
 	Next     *string
 	Api      pokeapi.Api[T]
+	// names listed so far, used for tab completion
+	SeenAreas    map[string]bool
+	SeenPokemons map[string]bool
+	Out          *Output
 }
 
-func NewCommandMap[T pokecache.Cache](api pokeapi.Api[T]) *CommandMap[T] {
+func NewCommandMap[T pokecache.Cache](api pokeapi.Api[T], out *Output) *CommandMap[T] {
 	next := fmt.Sprintf("%s/location-area?offset=%d&limit=%d", api.GetBaseUrl(), 0, api.GetConfig().Limit)
 	return &CommandMap[T]{
-		Next: &next,
-		Api:  api,
+		Next:         &next,
+		Api:          api,
+		SeenAreas:    map[string]bool{},
+		SeenPokemons: map[string]bool{},
+		Out:          out,
 	}
 }
 
-func (c *CommandMap[T]) PreviousArea() func(...string) error {
-	return func(...string) error {
+func (c *CommandMap[T]) PreviousArea() func(context.Context, ...string) error {
+	return func(ctx context.Context, _ ...string) error {
 		if c.Previous != nil {
-			return c.request(*c.Previous)
+			return c.request(ctx, *c.Previous)
 		} else {
-			fmt.Println("you're on the first page, consider using command: `map` (map forward) to display next 20 locations")
-			return nil
+			return notFound("you're on the first page, consider using command: `map` (map forward) to display next 20 locations", "")
 		}
 	}
 }
 
-func (c *CommandMap[T]) NextArea() func(...string) error {
-	return func(...string) error {
+func (c *CommandMap[T]) NextArea() func(context.Context, ...string) error {
+	return func(ctx context.Context, _ ...string) error {
 		if c.Next != nil {
-			return c.request(*c.Next)
+			return c.request(ctx, *c.Next)
 		} else {
-			fmt.Println("you're on the last page, consider using command: `mapb` (map back) to display previous 20 locations")
-			return nil
+			return notFound("you're on the last page, consider using command: `mapb` (map back) to display previous 20 locations", "")
 		}
 	}
 }
 
-func (c *CommandMap[T]) ExploreArea(params ...string) error {
+func (c *CommandMap[T]) ExploreArea(ctx context.Context, params ...string) error {
 	if len(params) == 0 {
 		return errors.New("invalid: no area to explore")
 	}
 	area := params[0]
-	fmt.Println("Exploring pastoria-city-area...")
-	response, err := c.Api.GetLocationAreaDetails(area)
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "Exploring %s...\n", area)
+	}
+	response, err := c.Api.GetLocationAreaDetails(ctx, area)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return suggestNotFound[T](ctx, c.Api, "location area", "location-area", area)
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println("Found Pokemon:")
+	if !c.Out.JSON() {
+		fmt.Fprintln(c.Out, "Found Pokemon:")
+	}
+	c.SeenAreas[area] = true
 	for _, encounter := range response.PokemonEncounters {
-		fmt.Printf(" - %s\n", encounter.Pokemon.Name)
+		c.SeenPokemons[encounter.Pokemon.Name] = true
+		if c.Out.JSON() {
+			if err := c.Out.emit(EncounterResult{area, encounter.Pokemon.Name, encounter.Pokemon.URL}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(c.Out, " - %s\n", encounter.Pokemon.Name)
+		}
 	}
 
 	return nil
 }
 
-func (c *CommandMap[T]) request(rawUrl string) error {
+func (c *CommandMap[T]) request(ctx context.Context, rawUrl string) error {
 	parsedUrl, err := url.Parse(rawUrl)
 	if err != nil {
 		panic(err)
@@ -74,14 +97,31 @@ func (c *CommandMap[T]) request(rawUrl string) error {
 	if err != nil {
 		return err
 	}
-	response, err := c.Api.GetLocationArea(offset)
+	response, err := c.Api.GetLocationArea(ctx, offset)
 	if err != nil {
 		return err
 	}
 	c.Next, c.Previous = response.Next, response.Previous
 	for _, area := range response.Results {
-		fmt.Println(area.Name)
+		c.SeenAreas[area.Name] = true
+		if c.Out.JSON() {
+			if err := c.Out.emit(AreaResult{area.Name, area.Url}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(c.Out, area.Name)
+		}
 	}
 
 	return nil
 }
+
+// AreaNames lists every area seen through map, mapb or explore
+func (c *CommandMap[T]) AreaNames() []string {
+	return slices.Sorted(maps.Keys(c.SeenAreas))
+}
+
+// PokemonNames lists every Pokemon found while exploring
+func (c *CommandMap[T]) PokemonNames() []string {
+	return slices.Sorted(maps.Keys(c.SeenPokemons))
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MaxParty is how many Pokemon the party holds
+const MaxParty = 6
+
+var errPartyUsage = errors.New("usage: party [add <pokemon> | remove <pokemon> | swap <pokemon> <pokemon> | lead <pokemon>]")
+
+// ShowParty shows the party, or changes it with one of its subcommands:
+//
+//	party add <pokemon>                 moves a Pokemon from the box to the end of the party
+//	party remove <pokemon>              sends a party member to the box
+//	party swap <pokemon> <pokemon>      swaps two party members, or a party member with a boxed one
+//	party lead <pokemon>                puts a Pokemon first
+func (c *CommandPokedex[T]) ShowParty(_ context.Context, params ...string) error {
+	if len(params) == 0 {
+		return c.printPokemons("Your party:", c.Party)
+	}
+	var refs []*CaughtPokemon
+	for _, ref := range params[1:] {
+		pokemon, err := c.FindPokemon(ref)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, pokemon)
+	}
+	var err error
+	switch sub := params[0]; {
+	case sub == "add" && len(refs) == 1:
+		err = c.AddToParty(refs[0].UID)
+	case sub == "remove" && len(refs) == 1:
+		err = c.RemoveFromParty(refs[0].UID)
+	case sub == "swap" && len(refs) == 2:
+		err = c.SwapParty(refs[0].UID, refs[1].UID)
+	case sub == "lead" && len(refs) == 1:
+		err = c.SetLead(refs[0].UID)
+	default:
+		return errPartyUsage
+	}
+	if err != nil || c.Out.JSON() {
+		return err
+	}
+	return c.printPokemons("Your party:", c.Party)
+}
+
+// ShowBox lists the Pokemon caught that are not in the party
+func (c *CommandPokedex[T]) ShowBox(context.Context, ...string) error {
+	return c.printPokemons("Your box:", c.Box())
+}
+
+// Box lists the UIDs of the Pokemon outside of the party
+func (c *CommandPokedex[T]) Box() []int {
+	var box []int
+	for _, uid := range c.UIDs() {
+		if !slices.Contains(c.Party, uid) {
+			box = append(box, uid)
+		}
+	}
+	return box
+}
+
+// Lead is the first Pokemon of the party, false if the party is empty
+func (c *CommandPokedex[T]) Lead() (CaughtPokemon, bool) {
+	if len(c.Party) == 0 {
+		return CaughtPokemon{}, false
+	}
+	return c.Pokemons[c.Party[0]], true
+}
+
+func (c *CommandPokedex[T]) AddToParty(uid int) error {
+	if slices.Contains(c.Party, uid) {
+		return fmt.Errorf("%s is already in your party", c.Pokemons[uid].Label())
+	}
+	if len(c.Party) >= MaxParty {
+		return errors.New("your party is full, use `party remove` or `party swap` first")
+	}
+	c.Party = append(c.Party, uid)
+	return nil
+}
+
+func (c *CommandPokedex[T]) RemoveFromParty(uid int) error {
+	i := slices.Index(c.Party, uid)
+	if i < 0 {
+		return fmt.Errorf("%s is not in your party", c.Pokemons[uid].Label())
+	}
+	c.Party = slices.Delete(c.Party, i, i+1)
+	return nil
+}
+
+// SwapParty swaps the slots of two party members. With a boxed Pokemon, it
+// takes the slot of the party member which goes to the box.
+func (c *CommandPokedex[T]) SwapParty(a, b int) error {
+	i, j := slices.Index(c.Party, a), slices.Index(c.Party, b)
+	switch {
+	case a == b:
+		return errors.New("cannot swap a Pokemon with itself")
+	case i >= 0 && j >= 0:
+		c.Party[i], c.Party[j] = b, a
+	case i >= 0:
+		c.Party[i] = b
+	case j >= 0:
+		c.Party[j] = a
+	default:
+		return errors.New("neither Pokemon is in your party")
+	}
+	return nil
+}
+
+// SetLead moves a Pokemon to the first slot, from the box if there is room
+func (c *CommandPokedex[T]) SetLead(uid int) error {
+	if i := slices.Index(c.Party, uid); i >= 0 {
+		c.Party = slices.Delete(c.Party, i, i+1)
+	} else if len(c.Party) >= MaxParty {
+		return errors.New("your party is full, use `party swap` to bring a Pokemon from the box")
+	}
+	c.Party = slices.Insert(c.Party, 0, uid)
+	return nil
+}
+
+// PartySubcommands lists the party subcommands, used for tab completion
+func (c *CommandPokedex[T]) PartySubcommands() []string {
+	return []string{"add", "lead", "remove", "swap"}
+}
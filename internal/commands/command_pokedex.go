@@ -1,20 +1,70 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/leobel/pokedexcli/internal/battle"
+	"github.com/leobel/pokedexcli/internal/inventory"
 	"github.com/leobel/pokedexcli/internal/pokeapi"
 	"github.com/leobel/pokedexcli/internal/pokecache"
+	"github.com/leobel/pokedexcli/internal/rng"
 )
 
+// CatchAttempt is a throw at a wild Pokemon
+type CatchAttempt struct {
+	Pokemon     pokeapi.Pokemon
+	CaptureRate int     // of the species, from 3 (legendaries) to 255
+	Ball        float64 // modifier of the ball thrown
+	Bonus       float64 // modifier of the berry fed before, 1 without one
+	HPRatio     float64 // current over max HP, 1 at full health
+	Status      string  // sleep, freeze, paralysis, poison or burn, empty if none
+}
+
 type PokemonCatcher interface {
 	TryToCatch(pokemon pokeapi.Pokemon) bool
 }
 
+// AttemptCatcher is a PokemonCatcher that also weighs the species capture
+// rate, the ball, the berry, the HP and the status of the throw
+type AttemptCatcher interface {
+	PokemonCatcher
+	TryToCatchAttempt(attempt CatchAttempt) bool
+}
+
+// tryToCatch throws at attempt.Pokemon with any PokemonCatcher, plain
+// catchers only get the Pokemon
+func tryToCatch(catcher PokemonCatcher, attempt CatchAttempt) bool {
+	if ac, ok := catcher.(AttemptCatcher); ok {
+		return ac.TryToCatchAttempt(attempt)
+	}
+	return catcher.TryToCatch(attempt.Pokemon)
+}
+
+// DefaultCaptureRate is the most common capture rate among species, used
+// when the species is not known
+const DefaultCaptureRate = 45
+
+// PokedexPokemonCatcher follows the catch formula of the mainline games,
+// drawing from Rand
 type PokedexPokemonCatcher struct {
-	lambda float64
+	Rand *rand.Rand
+}
+
+var statusModifiers = map[string]float64{
+	"sleep":     2.5,
+	"freeze":    2.5,
+	"paralysis": 1.5,
+	"poison":    1.5,
+	"burn":      1.5,
 }
 
 type CatcherOption[T pokecache.Cache] interface {
@@ -33,22 +83,126 @@ func WithPokemonCatcher[T pokecache.Cache](catcher PokemonCatcher) CatcherOption
 	return PokemonCatcherOption[T]{catcher}
 }
 
+type AreaOption[T pokecache.Cache] struct {
+	area func() *pokeapi.LocationAreaDetailsResponse
+}
+
+func (c AreaOption[T]) apply(cp *CommandPokedex[T]) {
+	cp.Area = c.area
+}
+
+type RandOption[T pokecache.Cache] struct {
+	r *rand.Rand
+}
+
+func (c RandOption[T]) apply(cp *CommandPokedex[T]) {
+	cp.Rand = c.r
+}
+
+// WithRand sets where the individual values and nature of caught Pokemon are
+// drawn from
+func WithRand[T pokecache.Cache](r *rand.Rand) CatcherOption[T] {
+	return RandOption[T]{r}
+}
+
+type ConfirmOption[T pokecache.Cache] struct {
+	confirm func(question string) bool
+}
+
+func (c ConfirmOption[T]) apply(cp *CommandPokedex[T]) {
+	cp.Confirm = c.confirm
+}
+
+// WithConfirm sets how the player is asked a yes or no question, i.e: whether
+// to let a Pokemon evolve
+func WithConfirm[T pokecache.Cache](confirm func(question string) bool) CatcherOption[T] {
+	return ConfirmOption[T]{confirm}
+}
+
+// WithArea sets where the player is, only the Pokemon living there can be
+// caught and the area is recorded on every catch
+func WithArea[T pokecache.Cache](area func() *pokeapi.LocationAreaDetailsResponse) CatcherOption[T] {
+	return AreaOption[T]{area}
+}
+
+// TryToCatch throws a poke-ball at a healthy Pokemon of an unknown species
 func (c PokedexPokemonCatcher) TryToCatch(pokemon pokeapi.Pokemon) bool {
-	experience := pokemon.BaseExperience
-	return rand.Float64() < math.Exp(-c.lambda*float64(experience))
+	return c.TryToCatchAttempt(CatchAttempt{Pokemon: pokemon, CaptureRate: DefaultCaptureRate, Ball: 1, Bonus: 1, HPRatio: 1})
+}
+
+func (c PokedexPokemonCatcher) TryToCatchAttempt(attempt CatchAttempt) bool {
+	return c.Rand.Float64() < CatchProbability(attempt)
+}
+
+// CatchProbability is the chance of attempt to succeed as in the mainline
+// games: the modified catch rate is (3*maxHP - 2*HP) / (3*maxHP) times the
+// capture rate and the ball, berry and status modifiers. A rate of 255 or
+// more always catches, otherwise the ball has to pass 4 shake checks.
+func CatchProbability(attempt CatchAttempt) float64 {
+	hp := min(max(attempt.HPRatio, 0), 1)
+	rate := (3 - 2*hp) / 3 * float64(attempt.CaptureRate) * attempt.Ball * attempt.Bonus
+	if status, ok := statusModifiers[attempt.Status]; ok {
+		rate *= status
+	}
+	if rate >= 255 {
+		return 1
+	}
+	if rate <= 0 {
+		return 0
+	}
+	shake := 1048560 / math.Sqrt(math.Sqrt(16711680/rate))
+	return math.Pow(shake/65536, 4)
 }
 
+// CaughtPokemon is a Pokemon in the Pokedex along with how it was caught.
+// UID tells apart Pokemon of the same species, ID being the species' one.
+type CaughtPokemon struct {
+	pokeapi.Pokemon
+	UID      int
+	Nickname string
+	CaughtAt time.Time
+	Location string
+	Level    int
+	Exp      int // total experience, at least what Level takes once it gained any
+	Traits   battle.Traits
+}
+
+// Label names the Pokemon for people, i.e: #3 pikachu (sparky)
+func (p CaughtPokemon) Label() string {
+	if p.Nickname != "" {
+		return fmt.Sprintf("#%d %s (%s)", p.UID, p.Name, p.Nickname)
+	}
+	return fmt.Sprintf("#%d %s", p.UID, p.Name)
+}
+
+// CommandPokedex keeps every Pokemon caught by UID. Up to MaxParty of them
+// are in the party, in order, the first one leading; the others are in the box.
 type CommandPokedex[T pokecache.Cache] struct {
-	Pokemons map[string]pokeapi.Pokemon
+	Pokemons map[int]CaughtPokemon
+	Party    []int
 	Api      pokeapi.Api[T]
 	Catcher  PokemonCatcher
+	Bag      inventory.Bag
+	Berry    *inventory.Item // fed to the next Pokemon thrown at
+	Rand     *rand.Rand      // individual values and natures are drawn from it
+	Area     func() *pokeapi.LocationAreaDetailsResponse
+	Confirm  func(question string) bool // asks the player, no answer is a no
+	Now      func() time.Time
+	Out      *Output
 }
 
-func NewCommandPokedex[T pokecache.Cache](api pokeapi.Api[T], catcherOpts ...CatcherOption[T]) *CommandPokedex[T] {
+func NewCommandPokedex[T pokecache.Cache](api pokeapi.Api[T], out *Output, catcherOpts ...CatcherOption[T]) *CommandPokedex[T] {
+	r := rng.New(rng.NewSeed()).Rand
 	pokedex := &CommandPokedex[T]{
-		Pokemons: map[string]pokeapi.Pokemon{},
+		Pokemons: map[int]CaughtPokemon{},
 		Api:      api,
-		Catcher:  PokedexPokemonCatcher{lambda: 0.005},
+		Catcher:  PokedexPokemonCatcher{r},
+		Rand:     r,
+		Bag:      inventory.NewBag(),
+		Area:     func() *pokeapi.LocationAreaDetailsResponse { return nil },
+		Confirm:  func(string) bool { return false },
+		Now:      time.Now,
+		Out:      out,
 	}
 	for _, opt := range catcherOpts {
 		opt.apply(pokedex)
@@ -57,48 +211,304 @@ func NewCommandPokedex[T pokecache.Cache](api pokeapi.Api[T], catcherOpts ...Cat
 	return pokedex
 }
 
-func (c *CommandPokedex[T]) ShowPokemons(...string) error {
-	fmt.Println("Your Pokedex:")
-	for _, pokemon := range c.Pokemons {
-		fmt.Printf(" - %s\n", pokemon.Name)
+// ShowPokemons lists every Pokemon caught, party and box alike, by UID
+func (c *CommandPokedex[T]) ShowPokemons(context.Context, ...string) error {
+	return c.printPokemons("Your Pokedex:", c.UIDs())
+}
+
+func (c *CommandPokedex[T]) printPokemons(header string, uids []int) error {
+	if c.Out.JSON() {
+		for _, uid := range uids {
+			if err := c.Out.emit(c.newPokemonResult(c.Pokemons[uid])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fmt.Fprintln(c.Out, header)
+	for _, uid := range uids {
+		fmt.Fprintf(c.Out, " - %s\n", c.Pokemons[uid].Label())
 	}
 	return nil
 }
 
-func (c *CommandPokedex[T]) CatchPokemon(params ...string) error {
+// UIDs lists the UIDs of every Pokemon caught, in the order they were caught
+func (c *CommandPokedex[T]) UIDs() []int {
+	return slices.Sorted(maps.Keys(c.Pokemons))
+}
+
+func (c *CommandPokedex[T]) newPokemonResult(pokemon CaughtPokemon) PokemonResult {
+	result := newPokemonResult(pokemon)
+	result.PartySlot = slices.Index(c.Party, pokemon.UID) + 1
+	return result
+}
+
+// FindPokemon looks a caught Pokemon up by #UID, nickname or name. A Pokemon
+// not caught is a *NotFoundError, a name shared by several Pokemon an error.
+func (c *CommandPokedex[T]) FindPokemon(ref string) (*CaughtPokemon, error) {
+	if uid, err := strconv.Atoi(strings.TrimPrefix(ref, "#")); err == nil {
+		if pokemon, ok := c.Pokemons[uid]; ok {
+			return &pokemon, nil
+		}
+		return nil, notFound(fmt.Sprintf("you have no Pokemon #%d", uid), "")
+	}
+	var found []CaughtPokemon
+	for _, uid := range c.UIDs() {
+		if pokemon := c.Pokemons[uid]; strings.EqualFold(pokemon.Nickname, ref) || strings.EqualFold(pokemon.Name, ref) {
+			found = append(found, pokemon)
+		}
+	}
+	switch len(found) {
+	case 0:
+		match, _ := closestMatch(ref, c.PokemonNames())
+		return nil, notFound("you have not caught that pokemon", match)
+	case 1:
+		return &found[0], nil
+	}
+	labels := make([]string, 0, len(found))
+	for _, pokemon := range found {
+		labels = append(labels, pokemon.Label())
+	}
+	return nil, fmt.Errorf("you have %d %s: %s, use its #id", len(found), ref, strings.Join(labels, ", "))
+}
+
+// CatchPokemon throws a ball at a Pokemon of the current area: catch <pokemon> [ball].
+// A poke-ball is thrown when no ball is given.
+func (c *CommandPokedex[T]) CatchPokemon(ctx context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no pokemon to catch")
+	}
 	name := params[0]
-	fmt.Printf("Throwing a Pokeball at %s...\n", name)
-	pokemon, err := c.Api.GetPokemon(name)
+	ballName := "poke-ball"
+	if len(params) > 1 {
+		ballName = params[1]
+	}
+	if item, err := inventory.Lookup(ballName); err != nil || item.Kind != inventory.Ball {
+		match, _ := closestMatch(ballName, c.BallNames())
+		return notFound(fmt.Sprintf("%s is not a ball", ballName), match)
+	}
+	if !c.Bag.Has(ballName) {
+		return fmt.Errorf("you have no %s left", ballName)
+	}
+	area := c.Area()
+	if area == nil {
+		return errNowhere
+	}
+	if names := areaPokemon(area); !slices.Contains(names, name) {
+		match, _ := closestMatch(name, names)
+		return notFound(fmt.Sprintf("there is no %s in %s", name, area.Name), match)
+	}
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "Throwing a %s at %s...\n", ballName, name)
+	}
+	pokemon, err := c.Api.GetPokemon(ctx, name)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return suggestNotFound[T](ctx, c.Api, "Pokemon", "pokemon", name)
+	}
 	if err != nil {
 		return err
 	}
-	if c.Catcher.TryToCatch(*pokemon) {
-		c.Pokemons[name] = *pokemon
-		fmt.Printf("%s was caught!\n", name)
-		fmt.Println("You may now inspect it with the inspect command.")
+	species, err := c.Api.GetPokemonSpecies(ctx, pokemon.Species.Name)
+	if err != nil {
+		return err
+	}
+	// everything a caught Pokemon needs is fetched before the ball is thrown,
+	// so a failing request does not cost the ball or the Pokemon
+	rate, err := c.Api.GetGrowthRate(ctx, species.GrowthRate.Name)
+	if err != nil {
+		return err
+	}
+	nature, err := c.rollNature(ctx)
+	if err != nil {
+		return err
+	}
+
+	ball, err := c.Bag.Take(ballName)
+	if err != nil {
+		return err
+	}
+	attempt := CatchAttempt{
+		Pokemon:     *pokemon,
+		CaptureRate: species.CaptureRate,
+		Ball:        ball.Modifier,
+		Bonus:       1,
+		HPRatio:     1, // wild Pokemon are met at full health
+	}
+	if c.Berry != nil {
+		attempt.Bonus = c.Berry.Modifier
+		c.Berry = nil
+	}
+	caught := tryToCatch(c.Catcher, attempt)
+	if !caught {
+		if c.Out.JSON() {
+			return c.Out.emit(CatchResult{name, ballName, false, 0})
+		}
+		fmt.Fprintf(c.Out, "%s escaped!\n", name)
+		return nil
+	}
+
+	lead, hasLead := c.Lead()
+	level := catchLevel(area, name)
+	uid := c.nextUID()
+	c.Pokemons[uid] = CaughtPokemon{
+		Pokemon:  *pokemon,
+		UID:      uid,
+		CaughtAt: c.Now(),
+		Location: area.Name,
+		Level:    level,
+		Exp:      expForLevel(rate, level),
+		Traits:   battle.RollTraits(c.Rand, nature),
+	}
+	inParty := len(c.Party) < MaxParty
+	if inParty {
+		c.Party = append(c.Party, uid)
+	}
+	if c.Out.JSON() {
+		if err := c.Out.emit(CatchResult{name, ballName, true, uid}); err != nil {
+			return err
+		}
 	} else {
-		fmt.Printf("%s escaped!\n", name)
+		fmt.Fprintf(c.Out, "%s was caught!\n", name)
+		if !inParty {
+			fmt.Fprintf(c.Out, "Your party is full, #%d %s was sent to the box.\n", uid, name)
+		}
+		fmt.Fprintln(c.Out, "You may now inspect it with the inspect command.")
+	}
+	// as in the later games, catching a Pokemon is worth as much as defeating it
+	if hasLead {
+		c.GainEffort(lead.UID, *pokemon)
+		return c.GainExp(ctx, lead.UID, ExpYield(pokemon.BaseExperience, level))
 	}
 	return nil
 }
 
-func (c *CommandPokedex[T]) InspectPokemon(params ...string) error {
-	name := params[0]
-	pokemon, ok := c.Pokemons[name]
-	if !ok {
-		fmt.Println("you have not caught that pokemon")
-	} else {
-		fmt.Printf("Name: %s\n", pokemon.Name)
-		fmt.Printf("Height: %d\n", pokemon.Height)
-		fmt.Printf("Weight: %d\n", pokemon.Weight)
-		fmt.Println("Stats:")
-		for _, stat := range pokemon.Stats {
-			fmt.Printf(" -%s: %d\n", stat.Stat.Name, stat.BaseStat)
+// rollNature picks one of the natures of the API at random
+func (c *CommandPokedex[T]) rollNature(ctx context.Context) (battle.Nature, error) {
+	names, err := c.Api.GetResourceNames(ctx, "nature")
+	if err != nil {
+		return battle.Nature{}, err
+	}
+	if len(names) == 0 {
+		return battle.Nature{}, errors.New("no natures to pick from")
+	}
+	nature, err := c.Api.GetNature(ctx, names[c.Rand.IntN(len(names))])
+	if err != nil {
+		return battle.Nature{}, err
+	}
+	return battle.NewNature(*nature), nil
+}
+
+// catchLevel is the lowest level name is met at in area
+func catchLevel(area *pokeapi.LocationAreaDetailsResponse, name string) int {
+	level := 0
+	for _, encounter := range area.PokemonEncounters {
+		if encounter.Pokemon.Name != name {
+			continue
 		}
-		fmt.Println("Types:")
-		for _, t := range pokemon.Types {
-			fmt.Printf(" - %s\n", t.Type.Name)
+		for _, version := range encounter.VersionDetails {
+			for _, detail := range version.EncounterDetails {
+				if detail.MinLevel > 0 && (level == 0 || detail.MinLevel < level) {
+					level = detail.MinLevel
+				}
+			}
 		}
 	}
+	if level == 0 {
+		return wildLevel
+	}
+	return level
+}
+
+// InspectPokemon shows a caught Pokemon: inspect <#id|nickname|name>. Its
+// stats are computed at its level, from its base stats, IVs, EVs and nature.
+func (c *CommandPokedex[T]) InspectPokemon(ctx context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no pokemon to inspect")
+	}
+	pokemon, err := c.FindPokemon(params[0])
+	if err != nil {
+		return err
+	}
+	if c.Out.JSON() {
+		return c.Out.emit(c.newPokemonResult(*pokemon))
+	}
+	fmt.Fprintf(c.Out, "Name: %s\n", pokemon.Name)
+	fmt.Fprintf(c.Out, "ID: #%d\n", pokemon.UID)
+	if pokemon.Nickname != "" {
+		fmt.Fprintf(c.Out, "Nickname: %s\n", pokemon.Nickname)
+	}
+	if pokemon.Location != "" {
+		fmt.Fprintf(c.Out, "Caught: %s at %s\n", pokemon.CaughtAt.Format(time.DateTime), pokemon.Location)
+	} else {
+		fmt.Fprintf(c.Out, "Caught: %s\n", pokemon.CaughtAt.Format(time.DateTime))
+	}
+	fmt.Fprintf(c.Out, "Level: %d (%d EXP)\n", pokemon.Level, pokemon.Exp)
+	if slot := slices.Index(c.Party, pokemon.UID); slot >= 0 {
+		fmt.Fprintf(c.Out, "In your party, slot %d\n", slot+1)
+	} else {
+		fmt.Fprintln(c.Out, "In the box")
+	}
+	fmt.Fprintf(c.Out, "Height: %d\n", pokemon.Height)
+	fmt.Fprintf(c.Out, "Weight: %d\n", pokemon.Weight)
+	if nature := pokemon.Traits.Nature; nature.Increased != "" {
+		fmt.Fprintf(c.Out, "Nature: %s (+%s, -%s)\n", nature.Name, nature.Increased, nature.Decreased)
+	} else if nature.Name != "" {
+		fmt.Fprintf(c.Out, "Nature: %s\n", nature.Name)
+	}
+	fmt.Fprintf(c.Out, "Stats at level %d:\n", pokemon.Level)
+	for _, stat := range pokemon.Stats {
+		name := stat.Stat.Name
+		fmt.Fprintf(c.Out, " -%s: %d (base %d, IV %d, EV %d)\n", name, pokemon.Traits.Stat(name, stat.BaseStat, pokemon.Level),
+			stat.BaseStat, pokemon.Traits.IVs[name], pokemon.Traits.EVs[name])
+	}
+	fmt.Fprintln(c.Out, "Types:")
+	for _, t := range pokemon.Types {
+		fmt.Fprintf(c.Out, " - %s\n", t.Type.Name)
+	}
+	return nil
+}
+
+// SetNickname gives a caught Pokemon a nickname, without one it clears it
+func (c *CommandPokedex[T]) SetNickname(_ context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("usage: nickname <pokemon> [nickname]")
+	}
+	pokemon, err := c.FindPokemon(params[0])
+	if err != nil {
+		return err
+	}
+	label := pokemon.Label()
+	pokemon.Nickname = strings.Join(params[1:], " ")
+	c.Pokemons[pokemon.UID] = *pokemon
+	if c.Out.JSON() {
+		return c.Out.emit(c.newPokemonResult(*pokemon))
+	}
+	if pokemon.Nickname == "" {
+		fmt.Fprintf(c.Out, "%s has no nickname now\n", label)
+	} else {
+		fmt.Fprintf(c.Out, "%s is now called %s\n", label, pokemon.Nickname)
+	}
 	return nil
 }
+
+// PokemonNames lists the names and nicknames of every Pokemon caught so far
+func (c *CommandPokedex[T]) PokemonNames() []string {
+	var names []string
+	for _, pokemon := range c.Pokemons {
+		names = append(names, pokemon.Name)
+		if pokemon.Nickname != "" {
+			names = append(names, pokemon.Nickname)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// nextUID is one more than the highest UID given so far
+func (c *CommandPokedex[T]) nextUID() int {
+	uid := 0
+	for id := range c.Pokemons {
+		uid = max(uid, id)
+	}
+	return uid + 1
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/battle"
+	"github.com/leobel/pokedexcli/internal/inventory"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+	"github.com/leobel/pokedexcli/internal/rng"
+	"github.com/leobel/pokedexcli/internal/savefile"
+)
+
+type CommandSave[T pokecache.Cache] struct {
+	Pokedex *CommandPokedex[T]
+	Store   *savefile.Store
+	Rand    *rng.Rand // its seed is saved, the load command restarts it from there
+	Out     *Output
+	// run once a slot replaced the Pokedex, i.e: ending a battle
+	OnLoad []func()
+}
+
+// SlotResult is printed by slots for every saved slot
+type SlotResult struct {
+	Slot    string    `json:"slot"`
+	SavedAt time.Time `json:"saved_at"`
+	Pokemon int       `json:"pokemon"`
+}
+
+func NewCommandSave[T pokecache.Cache](pokedex *CommandPokedex[T], store *savefile.Store, r *rng.Rand, out *Output) *CommandSave[T] {
+	return &CommandSave[T]{Pokedex: pokedex, Store: store, Rand: r, Out: out}
+}
+
+// Save writes the Pokedex and the bag to the given slot, or the default one
+func (c *CommandSave[T]) Save(_ context.Context, params ...string) error {
+	slot := slotParam(params)
+	n, err := c.SaveSlot(slot)
+	if err != nil {
+		return err
+	}
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "Saved %d Pokemon to slot %s\n", n, slot)
+	}
+	return nil
+}
+
+// SaveSlot writes the Pokedex to slot and returns how many Pokemon it holds
+func (c *CommandSave[T]) SaveSlot(slot string) (int, error) {
+	var pokemon []savefile.Pokemon
+	for _, uid := range c.Pokedex.UIDs() {
+		caught := c.Pokedex.Pokemons[uid]
+		pokemon = append(pokemon, savefile.Pokemon{
+			UID:      caught.UID,
+			Nickname: caught.Nickname,
+			CaughtAt: caught.CaughtAt,
+			Location: caught.Location,
+			Level:    caught.Level,
+			Exp:      caught.Exp,
+			IVs:      caught.Traits.IVs,
+			EVs:      caught.Traits.EVs,
+			Nature:   savefile.Nature(caught.Traits.Nature),
+			Data:     caught.Pokemon,
+		})
+	}
+	save := savefile.Save{SavedAt: time.Now(), Seed: c.Rand.Seed(), Pokemon: pokemon, Party: c.Pokedex.Party, Bag: c.Pokedex.Bag}
+	return len(pokemon), c.Store.Write(slot, save)
+}
+
+// Load replaces the Pokedex with the given slot, or the default one, and
+// restarts the random outcomes from the seed of the save so the run can be
+// replayed. Saves older than the seed have 0 and keep the current one.
+func (c *CommandSave[T]) Load(_ context.Context, params ...string) error {
+	slot := slotParam(params)
+	save, err := c.LoadSlot(slot)
+	if err != nil {
+		return err
+	}
+	if save.Seed != 0 {
+		c.Rand.Reseed(save.Seed)
+	}
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "Loaded %d Pokemon from slot %s (saved %s, seed %d)\n", len(save.Pokemon), slot, save.SavedAt.Local().Format(time.DateTime), c.Rand.Seed())
+	}
+	return nil
+}
+
+// LoadSlot replaces the Pokedex and the bag with slot, a missing slot is savefile.ErrNoSave
+func (c *CommandSave[T]) LoadSlot(slot string) (*savefile.Save, error) {
+	save, err := c.Store.Read(slot)
+	if err != nil {
+		return nil, err
+	}
+	pokemons := make(map[int]CaughtPokemon, len(save.Pokemon))
+	for _, p := range save.Pokemon {
+		pokemons[p.UID] = CaughtPokemon{
+			Pokemon:  p.Data,
+			UID:      p.UID,
+			Nickname: p.Nickname,
+			CaughtAt: p.CaughtAt,
+			Location: p.Location,
+			Level:    p.Level,
+			Exp:      p.Exp,
+			Traits:   battle.Traits{IVs: p.IVs, EVs: p.EVs, Nature: battle.Nature(p.Nature)},
+		}
+	}
+	c.Pokedex.Pokemons = pokemons
+	c.Pokedex.Party = nil
+	for _, uid := range save.Party {
+		if _, ok := pokemons[uid]; ok && len(c.Pokedex.Party) < MaxParty && !slices.Contains(c.Pokedex.Party, uid) {
+			c.Pokedex.Party = append(c.Pokedex.Party, uid)
+		}
+	}
+	c.Pokedex.Bag = save.Bag
+	if c.Pokedex.Bag == nil {
+		c.Pokedex.Bag = inventory.Bag{}
+	}
+	c.Pokedex.Berry = nil
+	for _, hook := range c.OnLoad {
+		hook()
+	}
+	return save, nil
+}
+
+func (c *CommandSave[T]) Slots(context.Context, ...string) error {
+	slots, err := c.Store.Slots()
+	if err != nil {
+		return err
+	}
+	if !c.Out.JSON() && len(slots) == 0 {
+		fmt.Fprintln(c.Out, "No saved slots")
+	}
+	for _, slot := range slots {
+		if c.Out.JSON() {
+			if err := c.Out.emit(SlotResult{slot.Name, slot.SavedAt, slot.Pokemon}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(c.Out, " - %s: %d Pokemon, saved %s\n", slot.Name, slot.Pokemon, slot.SavedAt.Local().Format(time.DateTime))
+		}
+	}
+	return nil
+}
+
+// SlotNames lists the saved slots, used for tab completion
+func (c *CommandSave[T]) SlotNames() []string {
+	// completion is best effort, an unreadable save dir just means no candidates
+	slots, _ := c.Store.Slots()
+	names := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		names = append(names, slot.Name)
+	}
+	return names
+}
+
+func slotParam(params []string) string {
+	if len(params) == 0 {
+		return savefile.DefaultSlot
+	}
+	return params[0]
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/leobel/pokedexcli/internal/rng"
+)
+
+type CommandSeed struct {
+	Rand *rng.Rand
+	Out  *Output
+}
+
+// SeedResult is printed by seed
+type SeedResult struct {
+	Seed uint64 `json:"seed"`
+}
+
+func NewCommandSeed(r *rng.Rand, out *Output) *CommandSeed {
+	return &CommandSeed{r, out}
+}
+
+// SetSeed restarts the random outcomes from a seed, without arguments it
+// shows the current one
+func (c *CommandSeed) SetSeed(_ context.Context, params ...string) error {
+	if len(params) == 0 {
+		if c.Out.JSON() {
+			return c.Out.emit(SeedResult{c.Rand.Seed()})
+		}
+		fmt.Fprintf(c.Out, "seed: %d\n", c.Rand.Seed())
+		return nil
+	}
+	seed, err := ParseSeed(params[0])
+	if err != nil {
+		return err
+	}
+	c.Rand.Reseed(seed)
+	return nil
+}
+
+func ParseSeed(s string) (uint64, error) {
+	seed, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seed %q, use a positive integer", s)
+	}
+	return seed, nil
+}
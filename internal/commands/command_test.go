@@ -2,15 +2,22 @@ package commands_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"io"
-	"os"
+	"fmt"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/leobel/pokedexcli/internal/battle"
 	"github.com/leobel/pokedexcli/internal/commands"
+	"github.com/leobel/pokedexcli/internal/inventory"
 	"github.com/leobel/pokedexcli/internal/pokeapi"
 	"github.com/leobel/pokedexcli/internal/repl"
+	"github.com/leobel/pokedexcli/internal/rng"
+	"github.com/leobel/pokedexcli/internal/savefile"
 )
 
 // --- Mock Cache ---
@@ -38,6 +45,14 @@ type mockApi[T mockCache] struct {
 	config                  pokeapi.Config
 	cache                   *T
 	getPokemonResponse      *pokeapi.Pokemon
+	getPokemonError         error
+	resourceNames           map[string][]string
+	speciesResp             *pokeapi.PokemonSpecies
+	evolutionChains         map[int]*pokeapi.EvolutionChain
+	moves                   map[string]*pokeapi.Move
+	pokemons                map[string]*pokeapi.Pokemon
+	getGrowthRateError      error
+	getNatureError          error
 	getLocationDetailsError error
 	locationDetailsResp     *pokeapi.LocationAreaDetailsResponse
 	locationAreaResponses   map[int]*pokeapi.LocationAreaResponse
@@ -49,6 +64,10 @@ func newMockApi[T mockCache](url string, cache *T, config pokeapi.Config) *mockA
 		config:                config,
 		cache:                 cache,
 		locationAreaResponses: map[int]*pokeapi.LocationAreaResponse{},
+		evolutionChains:       map[int]*pokeapi.EvolutionChain{},
+		moves:                 map[string]*pokeapi.Move{},
+		pokemons:              map[string]*pokeapi.Pokemon{},
+		resourceNames:         map[string][]string{"nature": {"hardy"}},
 	}
 }
 
@@ -60,15 +79,83 @@ func (api *mockApi[T]) GetConfig() pokeapi.Config {
 	return api.config
 }
 
-func (m *mockApi[T]) GetPokemon(name string) (*pokeapi.Pokemon, error) {
-	return m.getPokemonResponse, nil
+func (m *mockApi[T]) GetPokemon(ctx context.Context, name string) (*pokeapi.Pokemon, error) {
+	if pokemon, ok := m.pokemons[name]; ok {
+		return pokemon, nil
+	}
+	return m.getPokemonResponse, m.getPokemonError
+}
+
+func (m *mockApi[T]) GetResourceNames(ctx context.Context, resource string) ([]string, error) {
+	return m.resourceNames[resource], nil
+}
+
+func (m *mockApi[T]) GetPokemonSpecies(ctx context.Context, name string) (*pokeapi.PokemonSpecies, error) {
+	if m.speciesResp == nil {
+		return &pokeapi.PokemonSpecies{Name: name, CaptureRate: 45}, nil
+	}
+	return m.speciesResp, nil
+}
+
+func (m *mockApi[T]) GetEvolutionChain(ctx context.Context, id int) (*pokeapi.EvolutionChain, error) {
+	if chain, ok := m.evolutionChains[id]; ok {
+		return chain, nil
+	}
+	return nil, errors.New("not found")
 }
 
-func (m *mockApi[T]) GetLocationAreaDetails(area string) (*pokeapi.LocationAreaDetailsResponse, error) {
+func (m *mockApi[T]) GetMove(ctx context.Context, name string) (*pokeapi.Move, error) {
+	if move, ok := m.moves[name]; ok {
+		return move, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// GetGrowthRate returns the medium-fast rate whatever the name: level³ EXP
+func (m *mockApi[T]) GetGrowthRate(ctx context.Context, name string) (*pokeapi.GrowthRate, error) {
+	if m.getGrowthRateError != nil {
+		return nil, m.getGrowthRateError
+	}
+	rate := &pokeapi.GrowthRate{Name: "medium-fast", Formula: "x^3"}
+	rate.Levels = make([]struct {
+		Experience int `json:"experience"`
+		Level      int `json:"level"`
+	}, commands.MaxLevel)
+	for i := range rate.Levels {
+		level := i + 1
+		rate.Levels[i].Level = level
+		rate.Levels[i].Experience = level * level * level
+	}
+	rate.Levels[0].Experience = 0
+	return rate, nil
+}
+
+// GetNature returns a neutral nature whatever the name
+func (m *mockApi[T]) GetNature(ctx context.Context, name string) (*pokeapi.Nature, error) {
+	if m.getNatureError != nil {
+		return nil, m.getNatureError
+	}
+	return &pokeapi.Nature{Name: name}, nil
+}
+
+// GetType knows the damage relations of electric only, the others are neutral
+func (m *mockApi[T]) GetType(ctx context.Context, name string) (*pokeapi.Type, error) {
+	if name == "electric" {
+		var t pokeapi.Type
+		json.Unmarshal([]byte(`{"name":"electric","damage_relations":{
+			"double_damage_to":[{"name":"water"},{"name":"flying"}],
+			"half_damage_to":[{"name":"electric"},{"name":"grass"},{"name":"dragon"}],
+			"no_damage_to":[{"name":"ground"}]}}`), &t)
+		return &t, nil
+	}
+	return &pokeapi.Type{Name: name}, nil
+}
+
+func (m *mockApi[T]) GetLocationAreaDetails(ctx context.Context, area string) (*pokeapi.LocationAreaDetailsResponse, error) {
 	return m.locationDetailsResp, m.getLocationDetailsError
 }
 
-func (m *mockApi[T]) GetLocationArea(offset int) (*pokeapi.LocationAreaResponse, error) {
+func (m *mockApi[T]) GetLocationArea(ctx context.Context, offset int) (*pokeapi.LocationAreaResponse, error) {
 	if resp, ok := m.locationAreaResponses[offset]; ok {
 		return resp, nil
 	}
@@ -81,63 +168,68 @@ func (c AlwaysCatch) TryToCatch(pokeapi.Pokemon) bool {
 	return true
 }
 
-// --- Helpers to capture stdout ---
-func captureStdout(f func()) string {
-	old := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	f()
+// recordCatch catches everything and keeps the last attempt
+type recordCatch struct {
+	last *commands.CatchAttempt
+}
 
-	w.Close()
-	os.Stdout = old
+func (c recordCatch) TryToCatch(pokemon pokeapi.Pokemon) bool {
+	return c.TryToCatchAttempt(commands.CatchAttempt{Pokemon: pokemon})
+}
 
-	var buf bytes.Buffer
-	io.Copy(&buf, r)
-	return buf.String()
+func (c recordCatch) TryToCatchAttempt(attempt commands.CatchAttempt) bool {
+	*c.last = attempt
+	return true
 }
 
 func TestCommandExit(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
 	cache := newMockCache()
-	cmd := commands.NewCommandExit(cache)
+	cmd := commands.NewCommandExit(cache, commands.NewOutput(&out, commands.FormatText))
 
-	err := cmd.Exit()
-	if err == nil || !strings.Contains(err.Error(), "Closing the Pokedex") {
-		t.Fatalf("Exit() error = %v; want closing message", err)
+	err := cmd.Exit(context.Background())
+	if !errors.Is(err, repl.ErrExit) {
+		t.Fatalf("Exit() error = %v; want %v", err, repl.ErrExit)
+	}
+	if !strings.Contains(out.String(), "Closing the Pokedex") {
+		t.Errorf("Exit() printed %q; want closing message", out.String())
 	}
 	// Stop should clear store
 	cache.store["x"] = []byte("y")
-	cmd.Exit()
+	cmd.Exit(context.Background())
 	if len(cache.store) != 0 {
 		t.Errorf("Exit did not clear cache")
 	}
 }
 
 func TestCommandHelp(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
 	// prepare a fake command map
 	cmds := map[string]repl.CliCommand{
 		"a": {Name: "a", Description: "descA"},
 		"b": {Name: "b", Description: "descB"},
 	}
-	h := commands.NewCommandHelp(&cmds)
+	h := commands.NewCommandHelp(&cmds, commands.NewOutput(&out, commands.FormatText))
 
-	out := captureStdout(func() {
-		if err := h.Help(); err != nil {
-			t.Fatal(err)
-		}
-	})
+	if err := h.Help(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
 	// check ordering and content
-	if !strings.Contains(out, "Welcome to the Pokedex!") {
+	if !strings.Contains(out.String(), "Welcome to the Pokedex!") {
 		t.Error("Help output missing header")
 	}
 	// because keys sorted ["a","b"]
-	if !strings.Contains(out, "a: descA\nb: descB") {
-		t.Errorf("Help output wrong ordering or content: %q", out)
+	if !strings.Contains(out.String(), "a: descA\nb: descB") {
+		t.Errorf("Help output wrong ordering or content: %q", out.String())
 	}
 }
 
 func TestCommandMapNextPrevious(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
 	cache := newMockCache()
 	api := newMockApi("url", cache, pokeapi.Config{})
 
@@ -145,10 +237,7 @@ func TestCommandMapNextPrevious(t *testing.T) {
 	api.locationAreaResponses[0] = &pokeapi.LocationAreaResponse{
 		Next:     ptrString("url?offset=1"),
 		Previous: nil,
-		Results: []struct {
-			Name string `json:"name"`
-			Url  string `json:"url"`
-		}{
+		Results: []pokeapi.NamedResource{
 			{
 				Name: "foo",
 			},
@@ -157,10 +246,7 @@ func TestCommandMapNextPrevious(t *testing.T) {
 	api.locationAreaResponses[1] = &pokeapi.LocationAreaResponse{
 		Next:     ptrString("url?offset=2"),
 		Previous: ptrString("url?offset=0"),
-		Results: []struct {
-			Name string `json:"name"`
-			Url  string `json:"url"`
-		}{
+		Results: []pokeapi.NamedResource{
 			{
 				Name: "bar",
 			},
@@ -168,42 +254,44 @@ func TestCommandMapNextPrevious(t *testing.T) {
 	}
 
 	// build and test forward
-	cm := commands.NewCommandMap[*mockCache](api)
-	out := captureStdout(func() {
-		if err := cm.NextArea()(); err != nil {
-			t.Fatal(err)
-		}
-	})
+	cm := commands.NewCommandMap[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
+	if err := cm.NextArea()(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
 	// check ordering and content
-	if !strings.Contains(out, "foo") {
+	if !strings.Contains(out.String(), "foo") {
 		t.Error("Map output missing foo area")
 	}
 
-	out = captureStdout(func() {
-		if err := cm.NextArea()(); err != nil {
-			t.Fatal(err)
-		}
-	})
+	out.Reset()
+	if err := cm.NextArea()(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 	// check ordering and content
-	if !strings.Contains(out, "bar") {
+	if !strings.Contains(out.String(), "bar") {
 		t.Error("Map output missing bar area")
 	}
 
-	out = captureStdout(func() {
-		if err := cm.PreviousArea()(); err != nil {
-			t.Fatal(err)
-		}
-	})
+	out.Reset()
+	if err := cm.PreviousArea()(context.Background()); err != nil {
+		t.Fatal(err)
+	}
 
 	// check ordering and content
-	if !strings.Contains(out, "foo") {
+	if !strings.Contains(out.String(), "foo") {
 		t.Error("Map output missing foo area")
 	}
 
+	// every listed area is offered for completion
+	if names := cm.AreaNames(); len(names) != 2 || names[0] != "bar" || names[1] != "foo" {
+		t.Errorf("AreaNames() = %v; want [bar foo]", names)
+	}
 }
 
 func TestCommandMapExplore(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
 	cache := newMockCache()
 	api := newMockApi("url", cache, pokeapi.Config{})
 	api.locationDetailsResp = &pokeapi.LocationAreaDetailsResponse{
@@ -219,23 +307,32 @@ func TestCommandMapExplore(t *testing.T) {
 		},
 	}
 
-	cm := commands.NewCommandMap[*mockCache](api)
+	cm := commands.NewCommandMap[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
 
-	out := captureStdout(func() {
-		if err := cm.ExploreArea("some-area"); err != nil {
-			t.Fatal(err)
-		}
-	})
-	if !strings.Contains(out, "Pikachu") {
+	if err := cm.ExploreArea(context.Background(), "some-area"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Exploring some-area...\n") {
+		t.Errorf("ExploreArea did not name the explored area: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "Pikachu") {
 		t.Error("ExploreArea did not list Pikachu")
 	}
+	if names := cm.PokemonNames(); len(names) != 1 || names[0] != "Pikachu" {
+		t.Errorf("PokemonNames() = %v; want [Pikachu]", names)
+	}
+	if names := cm.AreaNames(); len(names) != 1 || names[0] != "some-area" {
+		t.Errorf("AreaNames() = %v; want [some-area]", names)
+	}
 	// error on no param
-	if err := cm.ExploreArea(); err == nil {
+	if err := cm.ExploreArea(context.Background()); err == nil {
 		t.Error("ExploreArea should error on missing param")
 	}
 }
 
 func TestCommandPokedex_ShowInspectCatch(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
 	cache := newMockCache()
 	api := newMockApi("url", cache, pokeapi.Config{})
 	api.getPokemonResponse = &pokeapi.Pokemon{
@@ -279,49 +376,906 @@ func TestCommandPokedex_ShowInspectCatch(t *testing.T) {
 		},
 	}
 
-	cp := commands.NewCommandPokedex(api, commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}))
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "Pikachu")))
 
 	// show empty first
-	out0 := captureStdout(func() {
-		cp.ShowPokemons()
-		if err := cp.ShowPokemons(); err != nil {
-			t.Fatal(err)
-		}
-	})
+	cp.ShowPokemons(context.Background())
+	if err := cp.ShowPokemons(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	out0 := out.String()
 	if !strings.Contains(out0, "Your Pokedex:") {
 		t.Error("ShowPokemons missing header")
 	}
 
 	// catch success (lambda small so success guaranteed)
-	out1 := captureStdout(func() {
-		if err := cp.CatchPokemon("Pikachu"); err != nil {
-			t.Fatal(err)
-		}
-	})
+	out.Reset()
+	if err := cp.CatchPokemon(context.Background(), "Pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	out1 := out.String()
 	if !strings.Contains(out1, "Pikachu was caught") {
 		t.Error("CatchPokemon did not report catch")
 	}
 
 	// inspect caught
-	out2 := captureStdout(func() {
-		if err := cp.InspectPokemon("Pikachu"); err != nil {
-			t.Fatal(err)
-		}
-	})
+	out.Reset()
+	if err := cp.InspectPokemon(context.Background(), "Pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	out2 := out.String()
 	if !strings.Contains(out2, "Name: Pikachu") {
 		t.Error("InspectPokemon did not print details")
 	}
+	if names := cp.PokemonNames(); len(names) != 1 || names[0] != "Pikachu" {
+		t.Errorf("PokemonNames() = %v; want [Pikachu]", names)
+	}
 
 	// inspect missing
-	out3 := captureStdout(func() {
-		if err := cp.InspectPokemon("Missing"); err != nil {
-			t.Fatal(err)
-		}
-	})
+	out.Reset()
+	printNotFound(t, cp.Out, cp.InspectPokemon(context.Background(), "Missing"))
+	out3 := out.String()
 	if !strings.Contains(out3, "not caught") {
 		t.Error("InspectPokemon should warn on missing")
 	}
 }
 
+func TestCommandEvolution(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	cache := newMockCache()
+	api := newMockApi("url", cache, pokeapi.Config{})
+
+	var species pokeapi.PokemonSpecies
+	json.Unmarshal([]byte(`{"name":"eevee","evolution_chain":{"url":"url/evolution-chain/67/"}}`), &species)
+	api.speciesResp = &species
+
+	var chain pokeapi.EvolutionChain
+	json.Unmarshal([]byte(`{"id":67,"chain":{
+		"species":{"name":"eevee"},
+		"evolves_to":[
+			{"species":{"name":"vaporeon"},"evolution_details":[{"trigger":{"name":"use-item"},"item":{"name":"water-stone"}}]},
+			{"species":{"name":"espeon"},"evolution_details":[{"trigger":{"name":"level-up"},"min_happiness":160,"time_of_day":"day"}]},
+			{"species":{"name":"sylveon"},"evolution_details":[{"trigger":{"name":"level-up"},"min_level":20}],
+			 "evolves_to":[{"species":{"name":"fakemon"},"evolution_details":[{"trigger":{"name":"trade"}}]}]}
+		]}}`), &chain)
+	api.evolutionChains[67] = &chain
+
+	ce := commands.NewCommandEvolution[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
+	if err := ce.ShowEvolution(context.Background(), "eevee"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"Evolution chain for eevee:\neevee\n",
+		"  -> vaporeon: use-item (item water-stone)\n",
+		"  -> espeon: level-up (friendship 160, during day)\n",
+		"  -> sylveon: level-up (min level 20)\n    -> fakemon: trade\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("ShowEvolution output missing %q, got: %q", e, out.String())
+		}
+	}
+
+	// error on no param
+	if err := ce.ShowEvolution(context.Background()); err == nil {
+		t.Error("ShowEvolution should error on missing param")
+	}
+}
+
+func TestCommandNotFoundSuggestions(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	cache := newMockCache()
+	api := newMockApi("url", cache, pokeapi.Config{})
+	notFound := &pokeapi.HTTPError{StatusCode: 404, URL: "url"}
+	api.getPokemonError = notFound
+	api.getLocationDetailsError = notFound
+	api.resourceNames["pokemon"] = []string{"bulbasaur", "pikachu", "raichu"}
+	api.resourceNames["location-area"] = []string{"canalave-city-area", "eterna-city-area"}
+
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu", "weedle", "missingno")))
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "pikahcu"))
+	if !strings.Contains(out.String(), "there is no pikahcu in viridian-forest-area — did you mean pikachu?") {
+		t.Errorf("CatchPokemon did not suggest pikachu: %q", out.String())
+	}
+
+	out.Reset()
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "mewtwo"))
+	if !strings.Contains(out.String(), "there is no mewtwo in viridian-forest-area\n") {
+		t.Errorf("CatchPokemon should not suggest unrelated names: %q", out.String())
+	}
+
+	// the API not knowing an area's Pokemon is reported too
+	out.Reset()
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "missingno"))
+	if !strings.Contains(out.String(), "no Pokemon named 'missingno'\n") {
+		t.Errorf("CatchPokemon did not report the unknown Pokemon: %q", out.String())
+	}
+
+	cm := commands.NewCommandMap[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
+	out.Reset()
+	printNotFound(t, cm.Out, cm.ExploreArea(context.Background(), "canalave-city"))
+	if !strings.Contains(out.String(), "no location area named 'canalave-city' — did you mean canalave-city-area?") {
+		t.Errorf("ExploreArea did not suggest canalave-city-area: %q", out.String())
+	}
+
+	// other errors are returned as is
+	api.getPokemonError = &pokeapi.HTTPError{StatusCode: 500, URL: "url"}
+	var httpErr *pokeapi.HTTPError
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); !errors.As(err, &httpErr) || httpErr.StatusCode != 500 {
+		t.Errorf("CatchPokemon should return non not found errors, got: %v", err)
+	}
+}
+
+// areaWith returns an area where the given Pokemon live, to be used with WithArea
+func areaWith(name string, pokemon ...string) func() *pokeapi.LocationAreaDetailsResponse {
+	area := &pokeapi.LocationAreaDetailsResponse{Name: name}
+	for _, p := range pokemon {
+		var encounter pokeapi.PokemonEncounters
+		encounter.Pokemon.Name = p
+		area.PokemonEncounters = append(area.PokemonEncounters, encounter)
+	}
+	return func() *pokeapi.LocationAreaDetailsResponse { return area }
+}
+
 // helper to get *string
 func ptrString(s string) *string { return &s }
+
+func TestCommandSaveLoad(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{ID: 25, Name: "pikachu"}
+	caughtAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	store := savefile.NewStore(t.TempDir())
+
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu")))
+	cp.Now = func() time.Time { return caughtAt }
+	cs := commands.NewCommandSave(cp, store, rng.New(42), commands.NewOutput(&out, commands.FormatText))
+
+	cp.CatchPokemon(context.Background(), "pikachu")
+	if err := cp.SetNickname(context.Background(), "pikachu", "sparky"); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := cs.Save(context.Background(), "kanto"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "Saved 1 Pokemon to slot kanto\n" {
+		t.Errorf("Save() printed %q", out.String())
+	}
+	if save, err := store.Read("kanto"); err != nil || save.Seed != 42 {
+		t.Errorf("Read(kanto) = %+v, %v; want seed 42 saved", save, err)
+	}
+
+	// a fresh pokedex gets everything back, the random outcomes restart from the saved seed
+	cp.Pokemons = map[int]commands.CaughtPokemon{}
+	cp.Party = nil
+	cs.Rand.Reseed(7)
+	cs.Rand.Uint64()
+	out.Reset()
+	if err := cs.Load(context.Background(), "kanto"); err != nil {
+		t.Fatal(err)
+	}
+	if cs.Rand.Seed() != 42 || cs.Rand.Uint64() != rng.New(42).Uint64() {
+		t.Errorf("Seed() = %d; want the sequence restarted from 42", cs.Rand.Seed())
+	}
+	if !strings.HasSuffix(out.String(), ", seed 42)\n") {
+		t.Errorf("Load() printed %q; want the seed shown", out.String())
+	}
+	if !slices.Equal(cp.Party, []int{1}) {
+		t.Errorf("Party = %v; want [1]", cp.Party)
+	}
+	pikachu, ok := cp.Pokemons[1]
+	if !ok || pikachu.ID != 25 || pikachu.Nickname != "sparky" || !pikachu.CaughtAt.Equal(caughtAt) || pikachu.Location != "viridian-forest-area" {
+		t.Errorf("loaded %+v; want pikachu nicknamed sparky caught at viridian-forest-area", pikachu)
+	}
+	if len(pikachu.Traits.IVs) != len(battle.StatNames) || pikachu.Traits.Nature.Name != "hardy" {
+		t.Errorf("loaded traits %+v; want the IVs and nature rolled when caught", pikachu.Traits)
+	}
+
+	out.Reset()
+	cp.InspectPokemon(context.Background(), "pikachu")
+	for _, line := range []string{"Nickname: sparky\n", "Caught: 2024-05-01 10:30:00 at viridian-forest-area\n", "Level: 5 (125 EXP)\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("InspectPokemon output missing %q: %q", line, out.String())
+		}
+	}
+
+	if names := cs.SlotNames(); len(names) != 1 || names[0] != "kanto" {
+		t.Errorf("SlotNames() = %v; want [kanto]", names)
+	}
+	if err := cs.Load(context.Background()); !errors.Is(err, savefile.ErrNoSave) {
+		t.Errorf("Load() of the empty default slot = %v; want %v", err, savefile.ErrNoSave)
+	}
+}
+
+func TestCommandExitHooks(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	saved := false
+	cmd := commands.NewCommandExit(newMockCache(), commands.NewOutput(&out, commands.FormatText),
+		func() error { saved = true; return nil },
+		func() error { return errors.New("disk full") },
+	)
+
+	if err := cmd.Exit(context.Background()); !errors.Is(err, repl.ErrExit) {
+		t.Fatalf("Exit() = %v; want %v", err, repl.ErrExit)
+	}
+	if !saved {
+		t.Error("Exit did not run its hooks")
+	}
+	if !strings.HasPrefix(out.String(), "disk full\n") {
+		t.Errorf("Exit should report failing hooks, got %q", out.String())
+	}
+}
+
+func TestCommandTravel(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.locationDetailsResp = &pokeapi.LocationAreaDetailsResponse{}
+	json.Unmarshal([]byte(`{"name":"viridian-forest-area","pokemon_encounters":[{"pokemon":{"name":"pikachu"}},{"pokemon":{"name":"weedle"}}]}`), api.locationDetailsResp)
+
+	ct := commands.NewCommandTravel[*mockCache](api, rng.New(1).Rand, commands.NewOutput(&out, commands.FormatText))
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](ct.CurrentArea))
+	api.getPokemonResponse = &pokeapi.Pokemon{Name: "pikachu"}
+
+	// nothing can be caught before travelling
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err == nil || !strings.Contains(err.Error(), "travel") {
+		t.Errorf("CatchPokemon() before travelling = %v; want to be told to travel", err)
+	}
+	if err := ct.Encounter(context.Background()); err == nil {
+		t.Error("Encounter() before travelling should fail")
+	}
+
+	if err := ct.Travel(context.Background(), "viridian-forest-area"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "You travelled to viridian-forest-area\n" {
+		t.Errorf("Travel() printed %q", out.String())
+	}
+	if names := ct.PokemonNames(); len(names) != 2 || names[0] != "pikachu" || names[1] != "weedle" {
+		t.Errorf("PokemonNames() = %v; want [pikachu weedle]", names)
+	}
+
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	if caught := cp.Pokemons[1]; caught.Location != "viridian-forest-area" {
+		t.Errorf("caught at %q; want viridian-forest-area", caught.Location)
+	}
+
+	// the area is kept when travelling to an unknown one
+	api.getLocationDetailsError = &pokeapi.HTTPError{StatusCode: 404, URL: "url"}
+	out.Reset()
+	printNotFound(t, ct.Out, ct.Travel(context.Background(), "nowhere"))
+	if !strings.Contains(out.String(), "no location area named 'nowhere'") || ct.CurrentArea().Name != "viridian-forest-area" {
+		t.Errorf("Travel(nowhere) printed %q and moved to %q", out.String(), ct.CurrentArea().Name)
+	}
+}
+
+func TestCommandEncounter(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.locationDetailsResp = &pokeapi.LocationAreaDetailsResponse{}
+	// red has the most walk slots, and of those only pikachu can be met
+	json.Unmarshal([]byte(`{"name":"viridian-forest-area","pokemon_encounters":[
+		{"pokemon":{"name":"pikachu"},"version_details":[
+			{"version":{"name":"red"},"encounter_details":[{"chance":5,"min_level":3,"max_level":3,"method":{"name":"walk"}}]},
+			{"version":{"name":"blue"},"encounter_details":[{"chance":0,"min_level":3,"max_level":5,"method":{"name":"walk"}}]}]},
+		{"pokemon":{"name":"weedle"},"version_details":[
+			{"version":{"name":"red"},"encounter_details":[{"chance":0,"min_level":3,"max_level":5,"method":{"name":"walk"}}]},
+			{"version":{"name":"blue"},"encounter_details":[{"chance":100,"min_level":3,"max_level":5,"method":{"name":"walk"}}]}]},
+		{"pokemon":{"name":"caterpie"},"version_details":[
+			{"version":{"name":"red"},"encounter_details":[{"chance":0,"min_level":3,"max_level":5,"method":{"name":"walk"}}]}]},
+		{"pokemon":{"name":"magikarp"},"version_details":[
+			{"version":{"name":"red"},"encounter_details":[{"chance":100,"min_level":5,"max_level":5,"method":{"name":"old-rod"}}]}]}]}`), api.locationDetailsResp)
+
+	ct := commands.NewCommandTravel[*mockCache](api, rng.New(1).Rand, commands.NewOutput(&out, commands.FormatText))
+	ct.Travel(context.Background(), "viridian-forest-area")
+	if methods := ct.Methods(); len(methods) != 2 || methods[0] != "old-rod" || methods[1] != "walk" {
+		t.Errorf("Methods() = %v; want [old-rod walk]", methods)
+	}
+
+	for range 20 {
+		out.Reset()
+		if err := ct.Encounter(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != "A wild pikachu appeared! (level 3, walk)\n" {
+			t.Fatalf("Encounter() printed %q; want pikachu", out.String())
+		}
+	}
+
+	out.Reset()
+	ct.Out.Format = commands.FormatJSON
+	if err := ct.Encounter(context.Background(), "old-rod"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"area":"viridian-forest-area","pokemon":"magikarp","level":5,"method":"old-rod"}`+"\n" {
+		t.Errorf("Encounter(old-rod) printed %q", out.String())
+	}
+
+	out.Reset()
+	printNotFound(t, ct.Out, ct.Encounter(context.Background(), "surf"))
+	if !strings.HasPrefix(out.String(), `{"error":"no wild Pokemon are found by surf`) {
+		t.Errorf("Encounter(surf) printed %q", out.String())
+	}
+}
+
+func TestCatchProbability(t *testing.T) {
+	t.Parallel()
+	attempt := func(captureRate int, ball, hp float64, status string) commands.CatchAttempt {
+		return commands.CatchAttempt{CaptureRate: captureRate, Ball: ball, Bonus: 1, HPRatio: hp, Status: status}
+	}
+	if p := commands.CatchProbability(attempt(3, inventory.MasterBallModifier, 1, "")); p != 1 {
+		t.Errorf("master ball = %v; want 1", p)
+	}
+	if p := commands.CatchProbability(attempt(255, 1, 0, "")); p != 1 {
+		t.Errorf("capture rate 255 at low HP = %v; want 1", p)
+	}
+
+	full := commands.CatchProbability(attempt(45, 1, 1, ""))
+	// a full HP Pokemon with capture rate 45 is caught ~6% of the times by a poke-ball
+	if full < 0.05 || full > 0.07 {
+		t.Errorf("poke-ball = %v; want about 0.06", full)
+	}
+	better := []struct {
+		name    string
+		attempt commands.CatchAttempt
+	}{
+		{"great-ball", attempt(45, 1.5, 1, "")},
+		{"higher capture rate", attempt(190, 1, 1, "")},
+		{"low HP", attempt(45, 1, 0.1, "")},
+		{"asleep", attempt(45, 1, 1, "sleep")},
+		{"poisoned", attempt(45, 1, 1, "poison")},
+		{"berry", commands.CatchAttempt{CaptureRate: 45, Ball: 1, Bonus: 1.5, HPRatio: 1}},
+	}
+	for _, b := range better {
+		if p := commands.CatchProbability(b.attempt); p <= full {
+			t.Errorf("%s = %v; want more than %v", b.name, p, full)
+		}
+	}
+	if half, low := commands.CatchProbability(attempt(45, 1, 0.5, "")), commands.CatchProbability(attempt(45, 1, 0.1, "")); low <= half {
+		t.Errorf("10%% HP = %v, 50%% HP = %v; lower HP should help more", low, half)
+	}
+	if asleep, poisoned := commands.CatchProbability(attempt(45, 1, 1, "sleep")), commands.CatchProbability(attempt(45, 1, 1, "poison")); asleep <= poisoned {
+		t.Errorf("asleep = %v, poisoned = %v; sleep should help more", asleep, poisoned)
+	}
+}
+
+func TestCommandCatchWithItems(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{Name: "pikachu"}
+	api.speciesResp = &pokeapi.PokemonSpecies{Name: "pikachu", CaptureRate: 190}
+	var last commands.CatchAttempt
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](recordCatch{&last}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu")))
+	cp.Bag = inventory.Bag{"poke-ball": 1, "ultra-ball": 1, "razz-berry": 1}
+
+	if err := cp.UseItem(context.Background(), "razz-berry"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.UseItem(context.Background(), "ultra-ball"); err == nil {
+		t.Error("UseItem(ultra-ball) should tell to throw it with catch")
+	}
+	out.Reset()
+	if err := cp.CatchPokemon(context.Background(), "pikachu", "ultra-ball"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Throwing a ultra-ball at pikachu...\n") {
+		t.Errorf("CatchPokemon() printed %q", out.String())
+	}
+	if last.CaptureRate != 190 || last.Ball != 2 || last.Bonus != 1.5 || last.HPRatio != 1 {
+		t.Errorf("attempt = %+v; want capture rate 190 with an ultra-ball and a razz-berry", last)
+	}
+
+	// the berry is gone after a throw, and so are the balls thrown
+	cp.CatchPokemon(context.Background(), "pikachu")
+	if last.Ball != 1 || last.Bonus != 1 {
+		t.Errorf("attempt = %+v; want a poke-ball without berry", last)
+	}
+	if len(cp.Bag) != 0 {
+		t.Errorf("Bag = %v; want it empty", cp.Bag)
+	}
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err == nil || !strings.Contains(err.Error(), "no poke-ball left") {
+		t.Errorf("CatchPokemon() with no ball = %v; want to be told there are none left", err)
+	}
+
+	out.Reset()
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "pikachu", "razz-berry"))
+	if out.String() != "razz-berry is not a ball\n" {
+		t.Errorf("CatchPokemon(razz-berry) printed %q", out.String())
+	}
+	out.Reset()
+	printNotFound(t, cp.Out, cp.UseItem(context.Background(), "raz-berry"))
+	if out.String() != "there is no item named raz-berry — did you mean razz-berry?\n" {
+		t.Errorf("UseItem(raz-berry) printed %q", out.String())
+	}
+}
+
+func TestCommandBag(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	cp := commands.NewCommandPokedex[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
+	cp.Bag = inventory.Bag{"poke-ball": 2, "razz-berry": 1}
+
+	if err := cp.ShowBag(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "Your bag:\n - poke-ball x2: a basic ball\n - razz-berry x1: feed it to make the next catch easier\n"
+	if out.String() != want {
+		t.Errorf("ShowBag() printed %q; want %q", out.String(), want)
+	}
+	if balls := cp.BallNames(); len(balls) != 1 || balls[0] != "poke-ball" {
+		t.Errorf("BallNames() = %v; want [poke-ball]", balls)
+	}
+}
+
+func TestPokedexPokemonCatcherSeeded(t *testing.T) {
+	t.Parallel()
+	attempt := commands.CatchAttempt{CaptureRate: 45, Ball: 1, Bonus: 1, HPRatio: 1}
+	throws := func(seed uint64) []bool {
+		catcher := commands.PokedexPokemonCatcher{Rand: rng.New(seed).Rand}
+		caught := make([]bool, 50)
+		for i := range caught {
+			caught[i] = catcher.TryToCatchAttempt(attempt)
+		}
+		return caught
+	}
+	first, again := throws(7), throws(7)
+	if !slices.Equal(first, again) {
+		t.Errorf("seed 7 gave %v then %v", first, again)
+	}
+	if !slices.Contains(first, true) || !slices.Contains(first, false) {
+		t.Errorf("50 throws at ~6%% gave %v; want some catches and some escapes", first)
+	}
+	if master := (commands.CatchAttempt{CaptureRate: 3, Ball: inventory.MasterBallModifier, Bonus: 1, HPRatio: 1}); !(commands.PokedexPokemonCatcher{Rand: rng.New(7).Rand}).TryToCatchAttempt(master) {
+		t.Error("a master ball should never fail")
+	}
+	// without the species a poke-ball is thrown at the most common capture rate
+	plain, attempted := commands.PokedexPokemonCatcher{Rand: rng.New(7).Rand}, commands.PokedexPokemonCatcher{Rand: rng.New(7).Rand}
+	for range 50 {
+		if plain.TryToCatch(pokeapi.Pokemon{}) != attempted.TryToCatchAttempt(attempt) {
+			t.Fatal("TryToCatch() should throw a poke-ball at capture rate 45")
+		}
+	}
+}
+
+func TestCommandSeed(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.locationDetailsResp = &pokeapi.LocationAreaDetailsResponse{}
+	json.Unmarshal([]byte(`{"name":"route-1","pokemon_encounters":[
+		{"pokemon":{"name":"pidgey"},"version_details":[{"version":{"name":"red"},"encounter_details":[{"chance":50,"min_level":2,"max_level":5,"method":{"name":"walk"}}]}]},
+		{"pokemon":{"name":"rattata"},"version_details":[{"version":{"name":"red"},"encounter_details":[{"chance":50,"min_level":2,"max_level":4,"method":{"name":"walk"}}]}]}]}`), api.locationDetailsResp)
+
+	random := rng.New(1)
+	cs := commands.NewCommandSeed(random, commands.NewOutput(&out, commands.FormatText))
+	ct := commands.NewCommandTravel[*mockCache](api, random.Rand, commands.NewOutput(&out, commands.FormatText))
+	ct.Travel(context.Background(), "route-1")
+	encounters := func() string {
+		out.Reset()
+		for range 10 {
+			ct.Encounter(context.Background())
+		}
+		return out.String()
+	}
+
+	if err := cs.SetSeed(context.Background(), "1234"); err != nil {
+		t.Fatal(err)
+	}
+	first := encounters()
+	cs.SetSeed(context.Background(), "1234")
+	if again := encounters(); again != first {
+		t.Errorf("seed 1234 met\n%s\nthen\n%s", first, again)
+	}
+
+	out.Reset()
+	cs.SetSeed(context.Background())
+	if out.String() != "seed: 1234\n" {
+		t.Errorf("SetSeed() printed %q", out.String())
+	}
+	if err := cs.SetSeed(context.Background(), "-1"); err == nil {
+		t.Error("SetSeed(-1) should fail")
+	}
+}
+
+func TestCommandBattle(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	for _, move := range []string{
+		`{"name":"thunder-shock","power":40,"accuracy":100,"pp":30,"damage_class":{"name":"special"},"type":{"name":"electric"}}`,
+		`{"name":"tackle","power":40,"accuracy":100,"pp":35,"damage_class":{"name":"physical"},"type":{"name":"normal"}}`,
+	} {
+		var m pokeapi.Move
+		json.Unmarshal([]byte(move), &m)
+		api.moves[m.Name] = &m
+	}
+	var pikachu, squirtle pokeapi.Pokemon
+	json.Unmarshal([]byte(`{"name":"pikachu","types":[{"type":{"name":"electric"}}],
+		"stats":[{"base_stat":35,"stat":{"name":"hp"}},{"base_stat":50,"stat":{"name":"special-attack"}},{"base_stat":90,"stat":{"name":"speed"}}],
+		"moves":[{"move":{"name":"thunder-shock"},"version_group_details":[{"level_learned_at":1,"move_learn_method":{"name":"level-up"}}]}]}`), &pikachu)
+	json.Unmarshal([]byte(`{"name":"squirtle","base_experience":63,"types":[{"type":{"name":"water"}}],
+		"stats":[{"base_stat":44,"stat":{"name":"hp"}},{"base_stat":65,"effort":1,"stat":{"name":"defense"}},{"base_stat":64,"stat":{"name":"special-defense"}},{"base_stat":43,"stat":{"name":"speed"}}],
+		"moves":[{"move":{"name":"tackle"},"version_group_details":[{"level_learned_at":1,"move_learn_method":{"name":"level-up"}}]}]}`), &squirtle)
+	api.getPokemonResponse = &squirtle
+
+	output := commands.NewOutput(&out, commands.FormatText)
+	cp := commands.NewCommandPokedex[*mockCache](api, output)
+	cp.Pokemons[1] = commands.CaughtPokemon{Pokemon: pikachu, UID: 1, Level: 50, Exp: 125000}
+	cp.Party = []int{1}
+	ct := commands.NewCommandTypes[*mockCache](api, output)
+	cb := commands.NewCommandBattle(api, cp, ct.TypeChart, rng.New(1).Rand, output)
+
+	if err := cb.Attack(context.Background(), "thunder-shock"); err == nil {
+		t.Error("Attack() outside a battle should fail")
+	}
+	printNotFound(t, cb.Out, cb.Start(context.Background(), "pikachuu", "squirtle"))
+	if out.String() != "you have not caught that pokemon — did you mean pikachu?\n" {
+		t.Errorf("Start(pikachuu) printed %q", out.String())
+	}
+
+	out.Reset()
+	if err := cb.Start(context.Background(), "pikachu", "squirtle"); err != nil {
+		t.Fatal(err)
+	}
+	want := "pikachu (level 50, electric, HP 95/95) vs squirtle (level 50, water, HP 104/104)\n" +
+		"Pick a move with `attack <move>`: thunder-shock (electric, PP 30/30)\n"
+	if out.String() != want {
+		t.Errorf("Start() printed %q; want %q", out.String(), want)
+	}
+	if err := cb.Start(context.Background(), "pikachu", "squirtle"); err == nil {
+		t.Error("Start() during a battle should fail")
+	}
+	if err := cb.Attack(context.Background(), "surf"); err == nil {
+		t.Error("Attack(surf) should fail, pikachu does not know it")
+	}
+
+	// pikachu is faster, its first line is always its own move
+	out.Reset()
+	if err := cb.Attack(context.Background(), "thunder-shock"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "pikachu used thunder-shock!\n  It's super effective!\n") {
+		t.Errorf("Attack() printed %q", out.String())
+	}
+
+	cb.Out.Format = commands.FormatJSON
+	for cb.Battle != nil {
+		out.Reset()
+		if err := cb.Attack(context.Background(), "thunder-shock"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// squirtle faints, pikachu gains 63*50/7 EXP but not enough to grow
+	lines := jsonLines(t, out.String())
+	if len(lines) < 2 || !strings.Contains(lines[len(lines)-2], `"fainted":true`) {
+		t.Fatalf("last turn = %v; want a Pokemon to faint", lines)
+	}
+	if last := lines[len(lines)-1]; last != `{"uid":1,"pokemon":"pikachu","gained":450,"exp":125450,"level":50}` {
+		t.Errorf("last line = %s; want pikachu to gain 450 EXP", last)
+	}
+	if evs := cp.Pokemons[1].Traits.EVs; evs["defense"] != 1 {
+		t.Errorf("EVs = %v; want the defense squirtle yields", evs)
+	}
+
+	cb.Out.Format = commands.FormatText
+	if err := cb.Flee(context.Background()); err == nil {
+		t.Error("Flee() outside a battle should fail")
+	}
+
+	// the lead battles when no Pokemon is given, boxed ones cannot battle
+	if err := cb.Start(context.Background(), "squirtle"); err != nil || cb.Battle.Player.Name != "pikachu" {
+		t.Errorf("Start(squirtle) = %v; want the lead pikachu to battle", err)
+	}
+	cb.Flee(context.Background())
+
+	// loading a slot ends the battle, the Pokemon battling may not be in it
+	cs := commands.NewCommandSave(cp, savefile.NewStore(t.TempDir()), rng.New(1), output)
+	cs.OnLoad = append(cs.OnLoad, cb.End)
+	if err := cs.Save(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	cb.Start(context.Background(), "squirtle")
+	out.Reset()
+	if err := cs.Load(context.Background()); err != nil || cb.Battle != nil {
+		t.Errorf("Load() = %v, battle = %v; want the battle ended", err, cb.Battle)
+	}
+	if !strings.HasPrefix(out.String(), "The battle against squirtle is over\n") {
+		t.Errorf("Load() printed %q", out.String())
+	}
+	cp.Party = nil
+	if err := cb.Start(context.Background(), "pikachu", "squirtle"); err == nil || !strings.Contains(err.Error(), "in the box") {
+		t.Errorf("Start() with a boxed Pokemon = %v; want to be told it is in the box", err)
+	}
+}
+
+func TestCommandWeaknessMatchup(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{}
+	json.Unmarshal([]byte(`{"name":"gyarados","types":[{"type":{"name":"water"}},{"type":{"name":"flying"}}]}`), api.getPokemonResponse)
+	ct := commands.NewCommandTypes[*mockCache](api, commands.NewOutput(&out, commands.FormatText))
+
+	if err := ct.Weakness(context.Background(), "gyarados"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "gyarados (water/flying) takes:\n  4x from electric\n" {
+		t.Errorf("Weakness() printed %q", out.String())
+	}
+
+	out.Reset()
+	if err := ct.Matchup(context.Background(), "electric", "grass"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "electric against grass (grass): 0.5x, not very effective\n" {
+		t.Errorf("Matchup(electric, grass) printed %q", out.String())
+	}
+	out.Reset()
+	printNotFound(t, ct.Out, ct.Matchup(context.Background(), "eletric", "grass"))
+	if out.String() != "no type named 'eletric' — did you mean electric?\n" {
+		t.Errorf("Matchup(eletric, grass) printed %q", out.String())
+	}
+
+	ct.Out.Format = commands.FormatJSON
+	out.Reset()
+	json.Unmarshal([]byte(`{"name":"diglett","types":[{"type":{"name":"ground"}}]}`), api.getPokemonResponse)
+	if err := ct.Weakness(context.Background(), "diglett"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), `{"pokemon":"diglett","types":["ground"],"x4":[],"x2":[],"x0.5":[],"x0.25":[],"x0":["electric"]}`)
+	out.Reset()
+	if err := ct.Matchup(context.Background(), "electric", "diglett"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), `{"attack":"electric","defender":"diglett","types":["ground"],"effectiveness":0}`)
+}
+
+func TestCommandParty(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{Name: "pikachu"}
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu")))
+
+	// two of the same species are told apart by their UID
+	for range commands.MaxParty + 1 {
+		out.Reset()
+		if err := cp.CatchPokemon(context.Background(), "pikachu"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(out.String(), "Your party is full, #7 pikachu was sent to the box.") {
+		t.Errorf("CatchPokemon() with a full party printed %q", out.String())
+	}
+	if len(cp.Pokemons) != 7 || !slices.Equal(cp.Party, []int{1, 2, 3, 4, 5, 6}) || !slices.Equal(cp.Box(), []int{7}) {
+		t.Fatalf("Pokemons = %d, Party = %v, Box = %v; want 7 with the last one boxed", len(cp.Pokemons), cp.Party, cp.Box())
+	}
+	if _, err := cp.FindPokemon("pikachu"); err == nil || !strings.Contains(err.Error(), "you have 7 pikachu") {
+		t.Errorf("FindPokemon(pikachu) = %v; want it to be ambiguous", err)
+	}
+	cp.SetNickname(context.Background(), "#7", "Sparky")
+	// the nickname keeps its case but is found however it is typed
+	if p, err := cp.FindPokemon("sparky"); err != nil || p.UID != 7 || p.Nickname != "Sparky" {
+		t.Errorf("FindPokemon(sparky) = %v, %v; want #7 nicknamed Sparky", p, err)
+	}
+	if p, err := cp.FindPokemon("Pikachu"); err == nil {
+		t.Errorf("FindPokemon(Pikachu) = %v; want it to be ambiguous", p)
+	}
+
+	party := func(params ...string) error {
+		out.Reset()
+		return cp.ShowParty(context.Background(), params...)
+	}
+	if err := party("add", "sparky"); err == nil {
+		t.Error("party add with a full party should fail")
+	}
+	if err := party("swap", "#2", "sparky"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cp.Party, []int{1, 7, 3, 4, 5, 6}) || !slices.Equal(cp.Box(), []int{2}) {
+		t.Errorf("after swap Party = %v, Box = %v", cp.Party, cp.Box())
+	}
+	if err := party("lead", "sparky"); err != nil {
+		t.Fatal(err)
+	}
+	if err := party("remove", "#3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := party("swap", "#4", "#5"); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cp.Party, []int{7, 1, 5, 4, 6}) {
+		t.Errorf("Party = %v; want [7 1 5 4 6]", cp.Party)
+	}
+	if !strings.HasPrefix(out.String(), "Your party:\n - #7 pikachu (Sparky)\n - #1 pikachu\n") {
+		t.Errorf("party printed %q", out.String())
+	}
+	if err := party("add", "#2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := party("remove", "#3"); err == nil {
+		t.Error("party remove of a boxed Pokemon should fail")
+	}
+	if err := party("drop", "#3"); err == nil {
+		t.Error("party drop should fail")
+	}
+	if lead, ok := cp.Lead(); !ok || lead.UID != 7 {
+		t.Errorf("Lead() = %v; want #7", lead.Label())
+	}
+
+	out.Reset()
+	cp.ShowBox(context.Background())
+	if out.String() != "Your box:\n - #3 pikachu\n" {
+		t.Errorf("ShowBox() printed %q", out.String())
+	}
+}
+
+func TestCommandGainExpEvolve(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	var species pokeapi.PokemonSpecies
+	json.Unmarshal([]byte(`{"name":"charmander","growth_rate":{"name":"medium-slow"},"evolution_chain":{"url":"url/evolution-chain/2/"}}`), &species)
+	api.speciesResp = &species
+	var chain pokeapi.EvolutionChain
+	json.Unmarshal([]byte(`{"id":2,"chain":{"species":{"name":"charmander"},"evolves_to":[
+		{"species":{"name":"charmeleon"},"evolution_details":[{"trigger":{"name":"level-up"},"min_level":16}]}]}}`), &chain)
+	api.evolutionChains[2] = &chain
+	api.pokemons["charmeleon"] = &pokeapi.Pokemon{ID: 5, Name: "charmeleon"}
+
+	answers := []bool{false, true}
+	var questions []string
+	confirm := func(question string) bool {
+		questions = append(questions, question)
+		answer := answers[0]
+		answers = answers[1:]
+		return answer
+	}
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText), commands.WithConfirm[*mockCache](confirm))
+	cp.Pokemons[1] = commands.CaughtPokemon{Pokemon: pokeapi.Pokemon{ID: 4, Name: "charmander"}, UID: 1, Nickname: "char", Level: 15, Exp: 15 * 15 * 15}
+
+	// 3375 + 100 EXP is still level 15, nothing to ask
+	if err := cp.GainExp(context.Background(), 1, 100); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "#1 charmander (char) gained 100 EXP\n" || len(questions) != 0 {
+		t.Errorf("GainExp(100) printed %q, asked %v", out.String(), questions)
+	}
+
+	// 16³ = 4096, the player says no
+	out.Reset()
+	if err := cp.GainExp(context.Background(), 1, 700); err != nil {
+		t.Fatal(err)
+	}
+	want := "#1 charmander (char) gained 700 EXP\n#1 charmander (char) grew to level 16!\n#1 charmander (char) did not evolve.\n"
+	if out.String() != want {
+		t.Errorf("GainExp(700) printed %q; want %q", out.String(), want)
+	}
+	if got := cp.Pokemons[1]; got.Name != "charmander" || got.Level != 16 || got.Exp != 4175 {
+		t.Errorf("Pokemon = %s level %d with %d EXP; want charmander level 16 with 4175 EXP", got.Name, got.Level, got.Exp)
+	}
+	if len(questions) != 1 || questions[0] != "#1 charmander (char) is evolving into charmeleon! Let it evolve?" {
+		t.Errorf("asked %q", questions)
+	}
+
+	// asked again on the next level, answered in JSON
+	out.Reset()
+	cp.Out.Format = commands.FormatJSON
+	if err := cp.GainExp(context.Background(), 1, 1000); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		`{"uid":1,"pokemon":"charmander","gained":1000,"exp":5175,"level":17}`,
+		`{"question":"#1 charmander (char) is evolving into charmeleon! Let it evolve?"}`,
+		`{"uid":1,"from":"charmander","to":"charmeleon","evolved":true}`,
+	)
+	if got := cp.Pokemons[1]; got.ID != 5 || got.Name != "charmeleon" || got.Nickname != "char" || got.Level != 17 {
+		t.Errorf("Pokemon = %+v; want char the level 17 charmeleon", got)
+	}
+	if len(questions) != 2 || questions[1] != "" {
+		t.Errorf("asked %q; want the JSON question left to the output", questions)
+	}
+}
+
+func TestCatchGivesLeadExp(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	var pidgey pokeapi.Pokemon
+	json.Unmarshal([]byte(`{"id":16,"name":"pidgey","base_experience":50,"stats":[
+		{"base_stat":40,"effort":0,"stat":{"name":"hp"}},{"base_stat":56,"effort":1,"stat":{"name":"speed"}}]}`), &pidgey)
+	api.getPokemonResponse = &pidgey
+	var area pokeapi.LocationAreaDetailsResponse
+	json.Unmarshal([]byte(`{"name":"route-1-area","pokemon_encounters":[{"pokemon":{"name":"pidgey"},"version_details":[
+		{"version":{"name":"red"},"encounter_details":[{"min_level":3,"max_level":4,"method":{"name":"walk"}},{"min_level":2,"max_level":2,"method":{"name":"walk"}}]}]}]}`), &area)
+	api.resourceNames["nature"] = []string{"hardy", "jolly"}
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatJSON),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](func() *pokeapi.LocationAreaDetailsResponse { return &area }),
+		commands.WithRand[*mockCache](rng.New(3).Rand))
+	cp.Pokemons[1] = commands.CaughtPokemon{Pokemon: pokeapi.Pokemon{Name: "pikachu"}, UID: 1, Level: 5, Exp: 125}
+	cp.Party = []int{1}
+
+	if err := cp.CatchPokemon(context.Background(), "pidgey"); err != nil {
+		t.Fatal(err)
+	}
+	// pidgey is caught at its lowest level in the area, 2, worth 50*2/7 EXP to the lead
+	assertLines(t, out.String(),
+		`{"pokemon":"pidgey","ball":"poke-ball","caught":true,"uid":2}`,
+		`{"uid":1,"pokemon":"pikachu","gained":14,"exp":139,"level":5}`,
+	)
+	if got := cp.Pokemons[2]; got.Level != 2 || got.Exp != 8 {
+		t.Errorf("pidgey is level %d with %d EXP; want level 2 with 8", got.Level, got.Exp)
+	}
+	// and the effort of the Pokemon caught
+	if evs := cp.Pokemons[1].Traits.EVs; evs["speed"] != 1 || evs["hp"] != 0 {
+		t.Errorf("lead EVs = %v; want 1 speed", evs)
+	}
+
+	// pidgey rolled its IVs and nature, inspect computes its stats from them
+	traits := cp.Pokemons[2].Traits
+	if len(traits.IVs) != len(battle.StatNames) || len(traits.EVs) != 0 || (traits.Nature.Name != "hardy" && traits.Nature.Name != "jolly") {
+		t.Fatalf("pidgey traits = %+v; want IVs, no EVs and a nature of the API", traits)
+	}
+	out.Reset()
+	cp.Out.Format = commands.FormatText
+	if err := cp.InspectPokemon(context.Background(), "pidgey"); err != nil {
+		t.Fatal(err)
+	}
+	hp := fmt.Sprintf(" -hp: %d (base 40, IV %d, EV 0)\n", (80+traits.IVs["hp"])*2/100+12, traits.IVs["hp"])
+	for _, line := range []string{"Nature: " + traits.Nature.Name + "\n", "Stats at level 2:\n", hp} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("InspectPokemon output missing %q: %q", line, out.String())
+		}
+	}
+}
+
+func TestCatchKeepsBallWhenRequestFails(t *testing.T) {
+	t.Parallel()
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{ID: 25, Name: "pikachu"}
+	api.getGrowthRateError = errors.New("connection reset")
+	var out bytes.Buffer
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatText),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu")))
+
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err == nil {
+		t.Fatal("CatchPokemon() should fail when the growth rate can't be fetched")
+	}
+	if cp.Bag["poke-ball"] != inventory.NewBag()["poke-ball"] || len(cp.Pokemons) != 0 {
+		t.Errorf("bag = %v, caught %d; want the ball kept and nothing caught", cp.Bag, len(cp.Pokemons))
+	}
+
+	api.getGrowthRateError = nil
+	api.getNatureError = errors.New("connection reset")
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err == nil {
+		t.Fatal("CatchPokemon() should fail when the nature can't be fetched")
+	}
+	if cp.Bag["poke-ball"] != inventory.NewBag()["poke-ball"] || len(cp.Pokemons) != 0 {
+		t.Errorf("bag = %v, caught %d; want the ball kept and nothing caught", cp.Bag, len(cp.Pokemons))
+	}
+}
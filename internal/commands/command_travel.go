@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+// CommandTravel keeps where the player is
+type CommandTravel[T pokecache.Cache] struct {
+	Api  pokeapi.Api[T]
+	Area *pokeapi.LocationAreaDetailsResponse // nil until the player travels somewhere
+	Rand *rand.Rand                           // wild Pokemon and their levels are drawn from it
+	Out  *Output
+}
+
+// WildResult is printed by encounter
+type WildResult struct {
+	Area    string `json:"area"`
+	Pokemon string `json:"pokemon"`
+	Level   int    `json:"level"`
+	Method  string `json:"method"`
+}
+
+func NewCommandTravel[T pokecache.Cache](api pokeapi.Api[T], r *rand.Rand, out *Output) *CommandTravel[T] {
+	return &CommandTravel[T]{Api: api, Rand: r, Out: out}
+}
+
+// Travel moves the player to an area, only its Pokemon can be caught there
+func (c *CommandTravel[T]) Travel(ctx context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no area to travel to")
+	}
+	name := params[0]
+	area, err := c.Api.GetLocationAreaDetails(ctx, name)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return suggestNotFound[T](ctx, c.Api, "location area", "location-area", name)
+	}
+	if err != nil {
+		return err
+	}
+	c.Area = area
+	if !c.Out.JSON() {
+		fmt.Fprintf(c.Out, "You travelled to %s\n", area.Name)
+	}
+	return nil
+}
+
+// CurrentArea is where the player is, nil if nowhere yet
+func (c *CommandTravel[T]) CurrentArea() *pokeapi.LocationAreaDetailsResponse {
+	return c.Area
+}
+
+// encounterSlot is a way a Pokemon can be met, chance being its weight
+type encounterSlot struct {
+	pokemon            string
+	chance             int
+	minLevel, maxLevel int
+}
+
+// Encounter looks for a wild Pokemon in the current area: encounter [method].
+// The method defaults to walk, or the first one the area has.
+func (c *CommandTravel[T]) Encounter(_ context.Context, params ...string) error {
+	if c.Area == nil {
+		return errNowhere
+	}
+	methods := c.Methods()
+	if len(methods) == 0 {
+		return notFound(fmt.Sprintf("there are no wild Pokemon in %s", c.Area.Name), "")
+	}
+	method := methods[0]
+	if len(params) > 0 {
+		method = params[0]
+	} else if slices.Contains(methods, "walk") {
+		method = "walk"
+	}
+	if !slices.Contains(methods, method) {
+		match, _ := closestMatch(method, methods)
+		return notFound(fmt.Sprintf("no wild Pokemon are found by %s in %s", method, c.Area.Name), match)
+	}
+
+	slots := encounterSlots(c.Area, method)
+	slot := pickSlot(c.Rand, slots)
+	level := slot.minLevel + c.Rand.IntN(slot.maxLevel-slot.minLevel+1)
+	if c.Out.JSON() {
+		return c.Out.emit(WildResult{c.Area.Name, slot.pokemon, level, method})
+	}
+	fmt.Fprintf(c.Out, "A wild %s appeared! (level %d, %s)\n", slot.pokemon, level, method)
+	return nil
+}
+
+// PokemonNames lists the Pokemon of the current area, used for tab completion
+func (c *CommandTravel[T]) PokemonNames() []string {
+	if c.Area == nil {
+		return nil
+	}
+	return areaPokemon(c.Area)
+}
+
+// Methods lists the encounter methods of the current area, used for tab completion
+func (c *CommandTravel[T]) Methods() []string {
+	if c.Area == nil {
+		return nil
+	}
+	methods := map[string]bool{}
+	for _, encounter := range c.Area.PokemonEncounters {
+		for _, version := range encounter.VersionDetails {
+			for _, detail := range version.EncounterDetails {
+				methods[detail.Method.Name] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(methods))
+}
+
+// encounterSlots returns the slots of method in a single game version, the
+// one with the most of them, so every Pokemon is weighted by the same table
+func encounterSlots(area *pokeapi.LocationAreaDetailsResponse, method string) []encounterSlot {
+	slotsByVersion := map[string][]encounterSlot{}
+	for _, encounter := range area.PokemonEncounters {
+		for _, version := range encounter.VersionDetails {
+			for _, detail := range version.EncounterDetails {
+				if detail.Method.Name != method {
+					continue
+				}
+				slot := encounterSlot{encounter.Pokemon.Name, detail.Chance, detail.MinLevel, max(detail.MinLevel, detail.MaxLevel)}
+				slotsByVersion[version.Version.Name] = append(slotsByVersion[version.Version.Name], slot)
+			}
+		}
+	}
+	var best []encounterSlot
+	for _, version := range slices.Sorted(maps.Keys(slotsByVersion)) {
+		if slots := slotsByVersion[version]; len(slots) > len(best) {
+			best = slots
+		}
+	}
+	return best
+}
+
+// pickSlot picks a slot at random, weighted by its chance
+func pickSlot(r *rand.Rand, slots []encounterSlot) encounterSlot {
+	total := 0
+	for _, slot := range slots {
+		total += max(slot.chance, 0)
+	}
+	if total == 0 {
+		return slots[r.IntN(len(slots))]
+	}
+	n := r.IntN(total)
+	for _, slot := range slots {
+		n -= max(slot.chance, 0)
+		if n < 0 {
+			return slot
+		}
+	}
+	return slots[len(slots)-1]
+}
+
+var errNowhere = errors.New("you are not in any area yet, use `travel <area>` first")
+
+// areaPokemon lists the Pokemon living in area
+func areaPokemon(area *pokeapi.LocationAreaDetailsResponse) []string {
+	names := make([]string, 0, len(area.PokemonEncounters))
+	for _, encounter := range area.PokemonEncounters {
+		names = append(names, encounter.Pokemon.Name)
+	}
+	return names
+}
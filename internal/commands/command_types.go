@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/leobel/pokedexcli/internal/battle"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+// CommandTypes tells how types fare against each other, from the chart of the API
+type CommandTypes[T pokecache.Cache] struct {
+	Api    pokeapi.Api[T]
+	Matrix *battle.Matrix // built on first use
+	Out    *Output
+}
+
+// WeaknessResult is printed by weakness, every attacking type being in the
+// bucket of its effectiveness and neutral ones left out
+type WeaknessResult struct {
+	Pokemon   string   `json:"pokemon"`
+	Types     []string `json:"types"`
+	Quadruple []string `json:"x4"`
+	Double    []string `json:"x2"`
+	Half      []string `json:"x0.5"`
+	Quarter   []string `json:"x0.25"`
+	Immune    []string `json:"x0"`
+}
+
+// MatchupResult is printed by matchup
+type MatchupResult struct {
+	Attack        string   `json:"attack"`
+	Defender      string   `json:"defender"`
+	Types         []string `json:"types"`
+	Effectiveness float64  `json:"effectiveness"`
+}
+
+func NewCommandTypes[T pokecache.Cache](api pokeapi.Api[T], out *Output) *CommandTypes[T] {
+	return &CommandTypes[T]{Api: api, Out: out}
+}
+
+// TypeChart returns the chart built from the type endpoint, fetching every
+// type the first time only
+func (c *CommandTypes[T]) TypeChart(ctx context.Context) (battle.TypeChart, error) {
+	if c.Matrix != nil {
+		return c.Matrix, nil
+	}
+	types := make([]pokeapi.Type, 0, len(battle.Types))
+	for _, name := range battle.Types {
+		t, err := c.Api.GetType(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+		types = append(types, *t)
+	}
+	matrix, err := battle.NewMatrix(types)
+	if err != nil {
+		return nil, err
+	}
+	c.Matrix = matrix
+	return matrix, nil
+}
+
+// Weakness shows how every type fares against a Pokemon: weakness <pokemon>
+func (c *CommandTypes[T]) Weakness(ctx context.Context, params ...string) error {
+	if len(params) == 0 {
+		return errors.New("invalid: no pokemon to show the weaknesses of")
+	}
+	pokemon, types, err := c.pokemonTypes(ctx, params[0])
+	if err != nil {
+		return err
+	}
+	chart, err := c.TypeChart(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := WeaknessResult{Pokemon: pokemon.Name, Types: types}
+	buckets := []struct {
+		label         string
+		effectiveness float64
+		types         *[]string
+	}{
+		{"4x", 4, &result.Quadruple},
+		{"2x", 2, &result.Double},
+		{"½x", 0.5, &result.Half},
+		{"¼x", 0.25, &result.Quarter},
+		{"0x", 0, &result.Immune},
+	}
+	for _, b := range buckets {
+		*b.types = []string{}
+		for _, attack := range battle.Types {
+			if battle.Effectiveness(chart, attack, types) == b.effectiveness {
+				*b.types = append(*b.types, attack)
+			}
+		}
+	}
+	if c.Out.JSON() {
+		return c.Out.emit(result)
+	}
+	fmt.Fprintf(c.Out, "%s (%s) takes:\n", pokemon.Name, strings.Join(types, "/"))
+	for _, b := range buckets {
+		if len(*b.types) > 0 {
+			fmt.Fprintf(c.Out, "  %s from %s\n", b.label, strings.Join(*b.types, ", "))
+		}
+	}
+	return nil
+}
+
+// Matchup shows how effective a type is against a Pokemon or another type:
+// matchup <attacker-type> <defender>
+func (c *CommandTypes[T]) Matchup(ctx context.Context, params ...string) error {
+	if len(params) < 2 {
+		return errors.New("invalid: matchup <attacker-type> <defender>")
+	}
+	attack, defender := params[0], params[1]
+	if !slices.Contains(battle.Types, attack) {
+		return notFoundNamed("type", attack, battle.Types)
+	}
+	types := []string{defender}
+	if !slices.Contains(battle.Types, defender) {
+		_, pokemonTypes, err := c.pokemonTypes(ctx, defender)
+		if err != nil {
+			return err
+		}
+		types = pokemonTypes
+	}
+	chart, err := c.TypeChart(ctx)
+	if err != nil {
+		return err
+	}
+
+	effectiveness := battle.Effectiveness(chart, attack, types)
+	if c.Out.JSON() {
+		return c.Out.emit(MatchupResult{attack, defender, types, effectiveness})
+	}
+	verdict := "neutral"
+	switch {
+	case effectiveness == 0:
+		verdict = "no effect"
+	case effectiveness > 1:
+		verdict = "super effective"
+	case effectiveness < 1:
+		verdict = "not very effective"
+	}
+	fmt.Fprintf(c.Out, "%s against %s (%s): %vx, %s\n", attack, defender, strings.Join(types, "/"), effectiveness, verdict)
+	return nil
+}
+
+// pokemonTypes fetches a Pokemon and its types, an unknown Pokemon is a
+// *NotFoundError
+func (c *CommandTypes[T]) pokemonTypes(ctx context.Context, name string) (*pokeapi.Pokemon, []string, error) {
+	pokemon, err := c.Api.GetPokemon(ctx, name)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		return nil, nil, suggestNotFound[T](ctx, c.Api, "Pokemon", "pokemon", name)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	types := make([]string, 0, len(pokemon.Types))
+	for _, t := range pokemon.Types {
+		types = append(types, t.Type.Name)
+	}
+	return pokemon, types, nil
+}
+
+// TypeNames lists the types, used for tab completion
+func (c *CommandTypes[T]) TypeNames() []string {
+	return battle.Types
+}
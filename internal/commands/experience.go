@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+// MaxLevel is the highest level a Pokemon can reach
+const MaxLevel = 100
+
+// wildLevel is the level of a Pokemon when its area does not tell, and of
+// those caught before levels were kept
+const wildLevel = 5
+
+// ExpResult is printed when a Pokemon gains experience
+type ExpResult struct {
+	UID     int    `json:"uid"`
+	Pokemon string `json:"pokemon"`
+	Gained  int    `json:"gained"`
+	Exp     int    `json:"exp"`
+	Level   int    `json:"level"`
+}
+
+// EvolutionResult is printed when a Pokemon could evolve, whether it did or not
+type EvolutionResult struct {
+	UID     int    `json:"uid"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Evolved bool   `json:"evolved"`
+}
+
+// QuestionResult is printed when the next input line is the answer to a question
+type QuestionResult struct {
+	Question string `json:"question"`
+}
+
+// ExpYield is the experience for defeating or catching a Pokemon, as in the
+// games before generation V: base experience times level over 7
+func ExpYield(baseExperience, level int) int {
+	return max(baseExperience*level/7, 1)
+}
+
+// expForLevel is the total experience needed to reach level on a growth rate
+func expForLevel(rate *pokeapi.GrowthRate, level int) int {
+	exp := 0
+	for _, l := range rate.Levels {
+		if l.Level <= level {
+			exp = max(exp, l.Experience)
+		}
+	}
+	return exp
+}
+
+// levelForExp is the level reached with exp on a growth rate
+func levelForExp(rate *pokeapi.GrowthRate, exp int) int {
+	level := 1
+	for _, l := range rate.Levels {
+		if l.Experience <= exp {
+			level = max(level, l.Level)
+		}
+	}
+	return min(level, MaxLevel)
+}
+
+// growthRate fetches the growth rate of a Pokemon's species
+func (c *CommandPokedex[T]) growthRate(ctx context.Context, pokemon pokeapi.Pokemon) (*pokeapi.PokemonSpecies, *pokeapi.GrowthRate, error) {
+	species, err := c.Api.GetPokemonSpecies(ctx, speciesName(pokemon))
+	if err != nil {
+		return nil, nil, err
+	}
+	rate, err := c.Api.GetGrowthRate(ctx, species.GrowthRate.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return species, rate, nil
+}
+
+// GainExp gives a caught Pokemon experience. When it grows a level it may
+// evolve, the player being asked first.
+func (c *CommandPokedex[T]) GainExp(ctx context.Context, uid, exp int) error {
+	pokemon := c.Pokemons[uid]
+	species, rate, err := c.growthRate(ctx, pokemon.Pokemon)
+	if err != nil {
+		return err
+	}
+	// a Pokemon caught before experience was kept starts at its level's
+	pokemon.Exp = max(pokemon.Exp, expForLevel(rate, pokemon.Level))
+	pokemon.Exp = min(pokemon.Exp+exp, expForLevel(rate, MaxLevel))
+	level := max(levelForExp(rate, pokemon.Exp), pokemon.Level)
+	grew := level > pokemon.Level
+	pokemon.Level = level
+	c.Pokemons[uid] = pokemon
+
+	if c.Out.JSON() {
+		if err := c.Out.emit(ExpResult{uid, pokemon.Name, exp, pokemon.Exp, pokemon.Level}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(c.Out, "%s gained %d EXP\n", pokemon.Label(), exp)
+		if grew {
+			fmt.Fprintf(c.Out, "%s grew to level %d!\n", pokemon.Label(), pokemon.Level)
+		}
+	}
+	if !grew {
+		return nil
+	}
+	return c.evolve(ctx, uid, species)
+}
+
+// GainEffort gives a caught Pokemon the effort values of one it defeated
+func (c *CommandPokedex[T]) GainEffort(uid int, defeated pokeapi.Pokemon) {
+	pokemon := c.Pokemons[uid]
+	pokemon.Traits.GainEffort(defeated)
+	c.Pokemons[uid] = pokemon
+}
+
+// evolve evolves a Pokemon whose level meets the condition of one of its
+// evolutions, if the player agrees
+func (c *CommandPokedex[T]) evolve(ctx context.Context, uid int, species *pokeapi.PokemonSpecies) error {
+	if species.EvolutionChain.URL == "" {
+		return nil
+	}
+	id, err := resourceID(species.EvolutionChain.URL)
+	if err != nil {
+		return err
+	}
+	chain, err := c.Api.GetEvolutionChain(ctx, id)
+	if err != nil {
+		return err
+	}
+	pokemon := c.Pokemons[uid]
+	link, ok := findChainLink(chain.Chain, species.Name)
+	if !ok {
+		return nil
+	}
+	for _, next := range link.EvolvesTo {
+		for _, detail := range next.EvolutionDetails {
+			if !levelUpEvolution(detail, pokemon.Level) {
+				continue
+			}
+			to := next.Species.Name
+			question := fmt.Sprintf("%s is evolving into %s! Let it evolve?", pokemon.Label(), to)
+			var evolved bool
+			if c.Out.JSON() {
+				if err := c.Out.emit(QuestionResult{question}); err != nil {
+					return err
+				}
+				evolved = c.Confirm("")
+			} else {
+				evolved = c.Confirm(question)
+			}
+			if evolved {
+				evolution, err := c.Api.GetPokemon(ctx, to)
+				if err != nil {
+					return err
+				}
+				pokemon.Pokemon = *evolution
+				c.Pokemons[uid] = pokemon
+			}
+			if c.Out.JSON() {
+				return c.Out.emit(EvolutionResult{uid, species.Name, to, evolved})
+			}
+			if evolved {
+				fmt.Fprintf(c.Out, "Congratulations! Your %s evolved into %s!\n", species.Name, to)
+			} else {
+				fmt.Fprintf(c.Out, "%s did not evolve.\n", pokemon.Label())
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// findChainLink looks for the stage of species in a chain
+func findChainLink(link pokeapi.ChainLink, species string) (pokeapi.ChainLink, bool) {
+	if link.Species.Name == species {
+		return link, true
+	}
+	for _, next := range link.EvolvesTo {
+		if found, ok := findChainLink(next, species); ok {
+			return found, true
+		}
+	}
+	return pokeapi.ChainLink{}, false
+}
+
+// levelUpEvolution reports whether detail is an evolution by levelling up
+// that level meets. Evolutions also needing something else, an item or
+// friendship or a time of day, cannot be met yet.
+func levelUpEvolution(detail pokeapi.EvolutionDetail, level int) bool {
+	if detail.Trigger.Name != "level-up" || detail.MinLevel == nil || level < *detail.MinLevel {
+		return false
+	}
+	return detail.Gender == nil && detail.HeldItem == nil && detail.Item == nil && detail.KnownMove == nil &&
+		detail.KnownMoveType == nil && detail.Location == nil && detail.MinAffection == nil &&
+		detail.MinBeauty == nil && detail.MinHappiness == nil && !detail.NeedsOverworldRain &&
+		detail.TimeOfDay == "" && detail.TradeSpecies == nil && !detail.TurnUpsideDown
+}
+
+// speciesName is the species of a Pokemon, its own name for data without one
+func speciesName(pokemon pokeapi.Pokemon) string {
+	if pokemon.Species.Name != "" {
+		return pokemon.Species.Name
+	}
+	return pokemon.Name
+}
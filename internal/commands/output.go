@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format is how commands print their results
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown output format %q, use text or json", s)
+}
+
+// Output is where commands write their results. In the JSON format every
+// result is a single JSON object on its own line and the free-form messages
+// meant for people are left out.
+type Output struct {
+	io.Writer
+	Format Format
+}
+
+func NewOutput(w io.Writer, format Format) *Output {
+	return &Output{w, format}
+}
+
+func (o *Output) JSON() bool {
+	return o.Format == FormatJSON
+}
+
+// emit writes v as one line of JSON
+func (o *Output) emit(v any) error {
+	return json.NewEncoder(o.Writer).Encode(v)
+}
+
+// AreaResult is printed by map and mapb for every location area
+type AreaResult struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// EncounterResult is printed by explore for every Pokemon found in the area
+type EncounterResult struct {
+	Area    string `json:"area"`
+	Pokemon string `json:"pokemon"`
+	URL     string `json:"url"`
+}
+
+// CatchResult is printed by catch
+type CatchResult struct {
+	Pokemon string `json:"pokemon"`
+	Ball    string `json:"ball"`
+	Caught  bool   `json:"caught"`
+	UID     int    `json:"uid,omitempty"` // of the Pokemon once caught
+}
+
+// ItemResult is printed by bag for every item in it
+type ItemResult struct {
+	Item  string `json:"item"`
+	Count int    `json:"count"`
+}
+
+// StatResult is a stat of a Pokemon, Value being computed at its level
+// from the base stat, IV, EV and nature
+type StatResult struct {
+	Name     string `json:"name"`
+	Value    int    `json:"value"`
+	BaseStat int    `json:"base_stat"`
+	IV       int    `json:"iv"`
+	EV       int    `json:"ev"`
+	Effort   int    `json:"effort"` // EVs given for defeating it
+}
+
+// PokemonResult is printed by inspect, and by pokedex for every caught Pokemon
+type PokemonResult struct {
+	UID            int          `json:"uid"`
+	ID             int          `json:"id"`
+	Name           string       `json:"name"`
+	Height         int          `json:"height"`
+	Weight         int          `json:"weight"`
+	BaseExperience int          `json:"base_experience"`
+	Stats          []StatResult `json:"stats"`
+	Types          []string     `json:"types"`
+	Nickname       string       `json:"nickname,omitempty"`
+	CaughtAt       time.Time    `json:"caught_at"`
+	Location       string       `json:"location,omitempty"`
+	Level          int          `json:"level"`
+	Exp            int          `json:"exp"`
+	Nature         string       `json:"nature,omitempty"`
+	PartySlot      int          `json:"party_slot,omitempty"` // 1 for the lead, left out for the box
+}
+
+// FormatResult is printed by format
+type FormatResult struct {
+	Format Format `json:"format"`
+}
+
+// CommandResult is printed by help for every command
+type CommandResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// EvolutionChainResult is printed by evolution, every stage listing the ones
+// it evolves to and how
+type EvolutionChainResult struct {
+	Species    string                 `json:"species"`
+	Conditions []string               `json:"conditions,omitempty"` // to evolve into this stage, any of them
+	EvolvesTo  []EvolutionChainResult `json:"evolves_to"`
+}
+
+// ErrorResult is printed instead of a result when there is nothing to show,
+// i.e: an unknown Pokemon, with the closest known name if any
+type ErrorResult struct {
+	Error      string `json:"error"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+func newPokemonResult(pokemon CaughtPokemon) PokemonResult {
+	result := PokemonResult{
+		UID:            pokemon.UID,
+		ID:             pokemon.ID,
+		Name:           pokemon.Name,
+		Height:         pokemon.Height,
+		Weight:         pokemon.Weight,
+		BaseExperience: pokemon.BaseExperience,
+		Stats:          make([]StatResult, 0, len(pokemon.Stats)),
+		Types:          make([]string, 0, len(pokemon.Types)),
+		Nickname:       pokemon.Nickname,
+		CaughtAt:       pokemon.CaughtAt,
+		Location:       pokemon.Location,
+		Level:          pokemon.Level,
+		Exp:            pokemon.Exp,
+		Nature:         pokemon.Traits.Nature.Name,
+	}
+	for _, stat := range pokemon.Stats {
+		name := stat.Stat.Name
+		value := pokemon.Traits.Stat(name, stat.BaseStat, pokemon.Level)
+		result.Stats = append(result.Stats, StatResult{name, value, stat.BaseStat, pokemon.Traits.IVs[name], pokemon.Traits.EVs[name], stat.Effort})
+	}
+	for _, t := range pokemon.Types {
+		result.Types = append(result.Types, t.Type.Name)
+	}
+	return result
+}
+
+// PrintError prints a failed command's error, as an ErrorResult in the JSON format
+func (o *Output) PrintError(err error) {
+	if !o.JSON() {
+		fmt.Fprintln(o, err)
+		return
+	}
+	result := ErrorResult{Error: err.Error()}
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		result = ErrorResult{notFound.Msg, notFound.Suggestion}
+	}
+	o.emit(result)
+}
+
+// NotFoundError is returned when there is nothing to show, i.e: an unknown
+// Pokemon, with the closest known name if any
+type NotFoundError struct {
+	Msg        string
+	Suggestion string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Suggestion == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s — did you mean %s?", e.Msg, e.Suggestion)
+}
+
+func notFound(msg, suggestion string) error {
+	return &NotFoundError{msg, suggestion}
+}
+
+// notFoundNamed is e.g: "no Pokemon named 'pikahcu' — did you mean pikachu?"
+func notFoundNamed(kind, name string, candidates []string) error {
+	suggestion, _ := closestMatch(name, candidates)
+	return notFound(fmt.Sprintf("no %s named '%s'", kind, name), suggestion)
+}
@@ -0,0 +1,234 @@
+package commands_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/commands"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/repl"
+	"github.com/leobel/pokedexcli/internal/rng"
+)
+
+// jsonLines checks every line of out is a JSON object and returns them
+func jsonLines(t *testing.T, out string) []string {
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	for _, line := range lines {
+		var v map[string]any
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Fatalf("output line %q is not a JSON object: %v", line, err)
+		}
+	}
+	return lines
+}
+
+func assertLines(t *testing.T, out string, expected ...string) {
+	t.Helper()
+	lines := jsonLines(t, out)
+	if len(lines) != len(expected) {
+		t.Fatalf("got %d lines %q; want %d", len(lines), lines, len(expected))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d = %s; want %s", i, lines[i], expected[i])
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	for _, s := range []string{"text", "json"} {
+		if f, err := commands.ParseFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+	if _, err := commands.ParseFormat("yaml"); err == nil {
+		t.Error("ParseFormat(yaml) should fail")
+	}
+}
+
+func TestJSONMap(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.locationAreaResponses[0] = &pokeapi.LocationAreaResponse{}
+	json.Unmarshal([]byte(`{"count":2,"next":null,"previous":null,"results":[
+		{"name":"canalave-city-area","url":"url/location-area/1/"},
+		{"name":"eterna-city-area","url":"url/location-area/2/"}]}`), api.locationAreaResponses[0])
+
+	cm := commands.NewCommandMap[*mockCache](api, commands.NewOutput(&out, commands.FormatJSON))
+	if err := cm.NextArea()(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		`{"name":"canalave-city-area","url":"url/location-area/1/"}`,
+		`{"name":"eterna-city-area","url":"url/location-area/2/"}`,
+	)
+
+	// the last page is reported as an error object
+	out.Reset()
+	printNotFound(t, cm.Out, cm.NextArea()(context.Background()))
+	lines := jsonLines(t, out.String())
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], `{"error":"you're on the last page`) {
+		t.Errorf("last page = %q; want an error object", lines)
+	}
+}
+
+func TestJSONExplore(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.locationDetailsResp = &pokeapi.LocationAreaDetailsResponse{}
+	json.Unmarshal([]byte(`{"name":"canalave-city-area","pokemon_encounters":[
+		{"pokemon":{"name":"tentacool","url":"url/pokemon/72/"}},
+		{"pokemon":{"name":"shellos","url":"url/pokemon/422/"}}]}`), api.locationDetailsResp)
+
+	cm := commands.NewCommandMap[*mockCache](api, commands.NewOutput(&out, commands.FormatJSON))
+	if err := cm.ExploreArea(context.Background(), "canalave-city-area"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		`{"area":"canalave-city-area","pokemon":"tentacool","url":"url/pokemon/72/"}`,
+		`{"area":"canalave-city-area","pokemon":"shellos","url":"url/pokemon/422/"}`,
+	)
+}
+
+func TestJSONCatchInspectPokedex(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonResponse = &pokeapi.Pokemon{}
+	json.Unmarshal([]byte(`{"id":25,"name":"pikachu","height":4,"weight":60,"base_experience":112,
+		"stats":[{"base_stat":35,"effort":0,"stat":{"name":"hp"}},{"base_stat":90,"effort":2,"stat":{"name":"speed"}}],
+		"types":[{"slot":1,"type":{"name":"electric"}}]}`), api.getPokemonResponse)
+	pikachu := `{"uid":1,"id":25,"name":"pikachu","height":4,"weight":60,"base_experience":112,` +
+		`"stats":[{"name":"hp","value":19,"base_stat":35,"iv":11,"ev":0,"effort":0},{"name":"speed","value":14,"base_stat":90,"iv":2,"ev":0,"effort":2}],` +
+		`"types":["electric"],"caught_at":"2024-05-01T10:30:00Z","location":"viridian-forest-area","level":5,"exp":125,"nature":"hardy","party_slot":1}`
+
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatJSON),
+		commands.WithPokemonCatcher[*mockCache](AlwaysCatch{}),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu")),
+		commands.WithRand[*mockCache](rng.New(1).Rand))
+	cp.Now = func() time.Time { return time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC) }
+	if err := cp.CatchPokemon(context.Background(), "pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), `{"pokemon":"pikachu","ball":"poke-ball","caught":true,"uid":1}`)
+
+	out.Reset()
+	if err := cp.InspectPokemon(context.Background(), "pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), pikachu)
+
+	out.Reset()
+	if err := cp.ShowPokemons(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), pikachu)
+
+	out.Reset()
+	printNotFound(t, cp.Out, cp.InspectPokemon(context.Background(), "pikachuu"))
+	assertLines(t, out.String(), `{"error":"you have not caught that pokemon","suggestion":"pikachu"}`)
+}
+
+func TestJSONNotFound(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	api.getPokemonError = &pokeapi.HTTPError{StatusCode: 404, URL: "url"}
+	api.resourceNames["pokemon"] = []string{"pikachu"}
+
+	cp := commands.NewCommandPokedex(api, commands.NewOutput(&out, commands.FormatJSON),
+		commands.WithArea[*mockCache](areaWith("viridian-forest-area", "pikachu", "pikachuu")))
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "pikahcu"))
+	assertLines(t, out.String(), `{"error":"there is no pikahcu in viridian-forest-area","suggestion":"pikachu"}`)
+
+	out.Reset()
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "mewtwo"))
+	assertLines(t, out.String(), `{"error":"there is no mewtwo in viridian-forest-area"}`)
+
+	out.Reset()
+	printNotFound(t, cp.Out, cp.CatchPokemon(context.Background(), "pikachuu"))
+	assertLines(t, out.String(), `{"error":"no Pokemon named 'pikachuu'","suggestion":"pikachu"}`)
+}
+
+func TestCommandFormat(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	out := commands.NewOutput(&buf, commands.FormatText)
+	cf := commands.NewCommandFormat(out)
+
+	if err := cf.SetFormat(context.Background(), "json"); err != nil {
+		t.Fatal(err)
+	}
+	if !out.JSON() {
+		t.Error("format json did not switch the output to JSON")
+	}
+	if err := cf.SetFormat(context.Background(), "xml"); err == nil {
+		t.Error("format xml should fail")
+	}
+	if err := cf.SetFormat(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, buf.String(), `{"format":"json"}`)
+
+	buf.Reset()
+	cf.SetFormat(context.Background(), "text")
+	cf.SetFormat(context.Background())
+	if buf.String() != "output format: text\n" {
+		t.Errorf("format = %q; want the current format", buf.String())
+	}
+}
+
+func TestJSONEvolutionHelp(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	output := commands.NewOutput(&out, commands.FormatJSON)
+	api := newMockApi("url", newMockCache(), pokeapi.Config{})
+	var species pokeapi.PokemonSpecies
+	json.Unmarshal([]byte(`{"name":"pichu","evolution_chain":{"url":"url/evolution-chain/10/"}}`), &species)
+	api.speciesResp = &species
+	var chain pokeapi.EvolutionChain
+	json.Unmarshal([]byte(`{"id":10,"chain":{"species":{"name":"pichu"},"evolves_to":[
+		{"species":{"name":"pikachu"},"evolution_details":[{"trigger":{"name":"level-up"},"min_happiness":220}],
+		 "evolves_to":[{"species":{"name":"raichu"},"evolution_details":[{"trigger":{"name":"use-item"},"item":{"name":"thunder-stone"}}]}]}]}}`), &chain)
+	api.evolutionChains[10] = &chain
+
+	if err := commands.NewCommandEvolution[*mockCache](api, output).ShowEvolution(context.Background(), "pichu"); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(), `{"species":"pichu","evolves_to":[{"species":"pikachu","conditions":["level-up (friendship 220)"],`+
+		`"evolves_to":[{"species":"raichu","conditions":["use-item (item thunder-stone)"],"evolves_to":[]}]}]}`)
+
+	out.Reset()
+	cmds := map[string]repl.CliCommand{"map": {Name: "map", Description: "Displays location areas"}, "exit": {Name: "exit", Description: "Exit the Pokedex"}}
+	if err := commands.NewCommandHelp(&cmds, output).Help(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, out.String(),
+		`{"name":"exit","description":"Exit the Pokedex"}`,
+		`{"name":"map","description":"Displays location areas"}`,
+	)
+
+	out.Reset()
+	output.PrintError(errors.New("unknown command \"fly\""))
+	assertLines(t, out.String(), `{"error":"unknown command \"fly\""}`)
+}
+
+// printNotFound prints the error a command failed with as the REPL does, it
+// must be a *NotFoundError
+func printNotFound(t *testing.T, out *commands.Output, err error) {
+	t.Helper()
+	var notFound *commands.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("err = %v; want a *NotFoundError", err)
+	}
+	out.PrintError(err)
+}
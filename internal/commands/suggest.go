@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"strings"
+
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+// suggestNotFound is the error for a name the API does not know, suggesting
+// the closest name of resource (e.g: "pokemon" or "location-area")
+func suggestNotFound[T pokecache.Cache](ctx context.Context, api pokeapi.Api[T], kind, resource, name string) error {
+	// suggestions are best effort, no names just means no suggestion
+	names, _ := api.GetResourceNames(ctx, resource)
+	return notFoundNamed(kind, name, names)
+}
+
+// closestMatch returns the candidate with the smallest edit distance to name,
+// as long as it is close enough to be a plausible typo. Otherwise it falls back
+// to the shortest candidate that starts with name (e.g: canalave-city)
+func closestMatch(name string, candidates []string) (string, bool) {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		d := levenshtein(name, candidate)
+		if bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance >= 0 && bestDistance <= max(2, len(name)/3) {
+		return best, true
+	}
+	prefixed := ""
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, name) && (prefixed == "" || len(candidate) < len(prefixed)) {
+			prefixed = candidate
+		}
+	}
+	return prefixed, prefixed != ""
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
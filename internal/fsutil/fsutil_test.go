@@ -0,0 +1,31 @@
+package fsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leobel/pokedexcli/internal/fsutil"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "save.json")
+	for _, data := range []string{"first", "second"} {
+		if err := fsutil.WriteFileAtomic(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(path); err != nil || string(got) != data {
+			t.Errorf("ReadFile() = %q, %v; want %q", got, err, data)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Stat() = %v, %v; want mode 0600", info, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("dir has %d entries; want the temp file gone", len(entries))
+	}
+	if err := fsutil.WriteFileAtomic(filepath.Join(dir, "missing", "save.json"), nil, 0o644); err == nil {
+		t.Error("WriteFileAtomic() into a missing dir should fail")
+	}
+}
@@ -0,0 +1,92 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+type Kind int
+
+const (
+	Ball Kind = iota
+	Berry
+)
+
+// Item is something the player can carry. Modifier multiplies the catch rate:
+// for a ball on the throw it is used for, for a berry on the next throw.
+type Item struct {
+	Name        string
+	Kind        Kind
+	Modifier    float64
+	Description string
+}
+
+// MasterBallModifier is high enough for any catch to succeed
+const MasterBallModifier = 255
+
+var items = map[string]Item{
+	"poke-ball":         {"poke-ball", Ball, 1, "a basic ball"},
+	"great-ball":        {"great-ball", Ball, 1.5, "a good ball, better than a poke-ball"},
+	"ultra-ball":        {"ultra-ball", Ball, 2, "a very good ball, better than a great-ball"},
+	"master-ball":       {"master-ball", Ball, MasterBallModifier, "catches any Pokemon without fail"},
+	"razz-berry":        {"razz-berry", Berry, 1.5, "feed it to make the next catch easier"},
+	"golden-razz-berry": {"golden-razz-berry", Berry, 2.5, "feed it to make the next catch much easier"},
+}
+
+var ErrUnknownItem = errors.New("unknown item")
+
+// Lookup returns the item called name
+func Lookup(name string) (Item, error) {
+	item, ok := items[name]
+	if !ok {
+		return Item{}, fmt.Errorf("%w %s", ErrUnknownItem, name)
+	}
+	return item, nil
+}
+
+// Names lists every known item, used for suggestions and completion
+func Names() []string {
+	return slices.Sorted(maps.Keys(items))
+}
+
+// Bag counts the items the player has by name
+type Bag map[string]int
+
+// NewBag returns what a new player starts with
+func NewBag() Bag {
+	return Bag{
+		"poke-ball":   20,
+		"great-ball":  5,
+		"ultra-ball":  3,
+		"master-ball": 1,
+		"razz-berry":  5,
+	}
+}
+
+// Take removes one item from the bag
+func (b Bag) Take(name string) (Item, error) {
+	item, err := Lookup(name)
+	if err != nil {
+		return Item{}, err
+	}
+	if b[name] <= 0 {
+		return Item{}, fmt.Errorf("you have no %s left", name)
+	}
+	b[name]--
+	if b[name] == 0 {
+		delete(b, name)
+	}
+	return item, nil
+}
+
+// Has reports whether there is at least one name in the bag
+func (b Bag) Has(name string) bool {
+	return b[name] > 0
+}
+
+// Names lists the items in the bag
+func (b Bag) Names() []string {
+	return slices.Sorted(maps.Keys(b))
+}
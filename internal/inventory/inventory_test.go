@@ -0,0 +1,36 @@
+package inventory_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/leobel/pokedexcli/internal/inventory"
+)
+
+func TestBagTake(t *testing.T) {
+	bag := inventory.Bag{"great-ball": 1}
+	item, err := bag.Take("great-ball")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Kind != inventory.Ball || item.Modifier != 1.5 {
+		t.Errorf("Take() = %+v; want a great-ball", item)
+	}
+	if bag.Has("great-ball") || len(bag.Names()) != 0 {
+		t.Errorf("Bag = %v; want it empty", bag)
+	}
+	if _, err := bag.Take("great-ball"); err == nil {
+		t.Error("Take() from an empty bag should fail")
+	}
+	if _, err := bag.Take("potion"); !errors.Is(err, inventory.ErrUnknownItem) {
+		t.Errorf("Take(potion) = %v; want %v", err, inventory.ErrUnknownItem)
+	}
+}
+
+func TestNewBag(t *testing.T) {
+	for _, name := range inventory.NewBag().Names() {
+		if _, err := inventory.Lookup(name); err != nil {
+			t.Errorf("new bag has %v", err)
+		}
+	}
+}
@@ -1,25 +1,32 @@
 package pokeapi
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/leobel/pokedexcli/internal/pokecache"
 )
 
-type LocationAreaResponse struct {
-	Count    int     `json:"count"`
-	Next     *string `json:"next"`
-	Previous *string `json:"previous"`
-	Results  []struct {
-		Name string `json:"name"`
-		Url  string `json:"url"`
-	} `json:"results"`
+// NamedResource is an entry of a NamedResourceList
+type NamedResource struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// NamedResourceList is a page of any resource listing, e.g: /pokemon or /type
+type NamedResourceList struct {
+	Count    int             `json:"count"`
+	Next     *string         `json:"next"`
+	Previous *string         `json:"previous"`
+	Results  []NamedResource `json:"results"`
 }
 
+type LocationAreaResponse = NamedResourceList
+
 type PokemonEncounters struct {
 	Pokemon struct {
 		Name string `json:"name"`
@@ -357,8 +364,188 @@ type Pokemon struct {
 	Weight int `json:"weight"`
 }
 
+type PokemonSpecies struct {
+	BaseHappiness  int `json:"base_happiness"`
+	CaptureRate    int `json:"capture_rate"`
+	EvolutionChain struct {
+		URL string `json:"url"`
+	} `json:"evolution_chain"`
+	EvolvesFromSpecies *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"evolves_from_species"`
+	GenderRate int `json:"gender_rate"`
+	GrowthRate struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"growth_rate"`
+	HatchCounter int    `json:"hatch_counter"`
+	ID           int    `json:"id"`
+	IsBaby       bool   `json:"is_baby"`
+	IsLegendary  bool   `json:"is_legendary"`
+	IsMythical   bool   `json:"is_mythical"`
+	Name         string `json:"name"`
+	Order        int    `json:"order"`
+	Varieties    []struct {
+		IsDefault bool `json:"is_default"`
+		Pokemon   struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"pokemon"`
+	} `json:"varieties"`
+}
+
+type EvolutionDetail struct {
+	Gender   *int `json:"gender"`
+	HeldItem *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"held_item"`
+	Item *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"item"`
+	KnownMove *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"known_move"`
+	KnownMoveType *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"known_move_type"`
+	Location *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"location"`
+	MinAffection       *int   `json:"min_affection"`
+	MinBeauty          *int   `json:"min_beauty"`
+	MinHappiness       *int   `json:"min_happiness"`
+	MinLevel           *int   `json:"min_level"`
+	NeedsOverworldRain bool   `json:"needs_overworld_rain"`
+	TimeOfDay          string `json:"time_of_day"`
+	TradeSpecies       *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"trade_species"`
+	Trigger struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"trigger"`
+	TurnUpsideDown bool `json:"turn_upside_down"`
+}
+
+type ChainLink struct {
+	EvolutionDetails []EvolutionDetail `json:"evolution_details"`
+	EvolvesTo        []ChainLink       `json:"evolves_to"`
+	IsBaby           bool              `json:"is_baby"`
+	Species          struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"species"`
+}
+
+type EvolutionChain struct {
+	BabyTriggerItem *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"baby_trigger_item"`
+	Chain ChainLink `json:"chain"`
+	ID    int       `json:"id"`
+}
+
+// Move is a move a Pokemon can learn. Power and Accuracy are nil for moves
+// that deal no direct damage or never miss.
+type Move struct {
+	Accuracy    *int `json:"accuracy"`
+	DamageClass struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"damage_class"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Power    *int   `json:"power"`
+	PP       int    `json:"pp"`
+	Priority int    `json:"priority"`
+	Type     struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"type"`
+}
+
+// Type is a Pokemon or move type, with how it fares against the others
+type Type struct {
+	DamageRelations struct {
+		DoubleDamageFrom []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"double_damage_from"`
+		DoubleDamageTo []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"double_damage_to"`
+		HalfDamageFrom []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"half_damage_from"`
+		HalfDamageTo []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"half_damage_to"`
+		NoDamageFrom []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"no_damage_from"`
+		NoDamageTo []struct {
+			Name string `json:"name"`
+			URL  string `json:"url"`
+		} `json:"no_damage_to"`
+	} `json:"damage_relations"`
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GrowthRate is how much experience a species needs for every level
+type GrowthRate struct {
+	Formula string `json:"formula"`
+	ID      int    `json:"id"`
+	Levels  []struct {
+		Experience int `json:"experience"`
+		Level      int `json:"level"`
+	} `json:"levels"`
+	Name string `json:"name"`
+}
+
+// Nature raises one stat by 10% and lowers another one by as much, neutral
+// natures having neither
+type Nature struct {
+	DecreasedStat *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"decreased_stat"`
+	HatesFlavor *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"hates_flavor"`
+	ID            int `json:"id"`
+	IncreasedStat *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"increased_stat"`
+	LikesFlavor *struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"likes_flavor"`
+	Name string `json:"name"`
+}
+
 type Config struct {
-	Limit int
+	Limit   int
+	Timeout time.Duration // per request, zero means no timeout
+	Client  *http.Client  // nil means http.DefaultClient
+	Retry   RetryPolicy
+	Limiter *RateLimiter // nil means no rate limiting
+	// serve stale cache entries right away and revalidate them in the background
+	StaleWhileRevalidate bool
 }
 
 type Option interface {
@@ -375,10 +562,79 @@ func WithLimit(limit int) limitOption {
 	return limitOption(limit)
 }
 
+type timeoutOption time.Duration
+
+func (t timeoutOption) apply(conf *Config) {
+	conf.Timeout = time.Duration(t)
+}
+
+func WithTimeout(timeout time.Duration) timeoutOption {
+	return timeoutOption(timeout)
+}
+
+type clientOption struct {
+	client *http.Client
+}
+
+func (c clientOption) apply(conf *Config) {
+	conf.Client = c.client
+}
+
+func WithHTTPClient(client *http.Client) clientOption {
+	return clientOption{client}
+}
+
+func WithTransport(transport http.RoundTripper) clientOption {
+	return clientOption{&http.Client{Transport: transport}}
+}
+
+type retryOption RetryPolicy
+
+func (r retryOption) apply(conf *Config) {
+	conf.Retry = RetryPolicy(r)
+}
+
+func WithRetry(maxRetries int, baseDelay, maxDelay time.Duration) retryOption {
+	return retryOption{maxRetries, baseDelay, maxDelay}
+}
+
+type rateLimitOption struct {
+	limiter *RateLimiter
+}
+
+func (r rateLimitOption) apply(conf *Config) {
+	conf.Limiter = r.limiter
+}
+
+type staleWhileRevalidateOption bool
+
+func (s staleWhileRevalidateOption) apply(conf *Config) {
+	conf.StaleWhileRevalidate = bool(s)
+}
+
+// WithStaleWhileRevalidate needs a pokecache.MetaCache that keeps stale entries
+// (e.g: pokecache.WithStaleTTL), other caches never report an entry as stale
+func WithStaleWhileRevalidate() staleWhileRevalidateOption {
+	return staleWhileRevalidateOption(true)
+}
+
+// WithRateLimit allows up to rate requests per second with bursts of burst
+// requests, both must be positive
+func WithRateLimit(rate float64, burst int) rateLimitOption {
+	return rateLimitOption{NewRateLimiter(rate, burst)}
+}
+
 type Api[T pokecache.Cache] interface {
-	GetPokemon(name string) (*Pokemon, error)
-	GetLocationAreaDetails(area string) (*LocationAreaDetailsResponse, error)
-	GetLocationArea(offset int) (*LocationAreaResponse, error)
+	GetPokemon(ctx context.Context, name string) (*Pokemon, error)
+	GetPokemonSpecies(ctx context.Context, name string) (*PokemonSpecies, error)
+	GetEvolutionChain(ctx context.Context, id int) (*EvolutionChain, error)
+	GetMove(ctx context.Context, name string) (*Move, error)
+	GetType(ctx context.Context, name string) (*Type, error)
+	GetGrowthRate(ctx context.Context, name string) (*GrowthRate, error)
+	GetNature(ctx context.Context, name string) (*Nature, error)
+	GetLocationAreaDetails(ctx context.Context, area string) (*LocationAreaDetailsResponse, error)
+	GetLocationArea(ctx context.Context, offset int) (*LocationAreaResponse, error)
+	GetResourceNames(ctx context.Context, resource string) ([]string, error)
 	GetBaseUrl() string
 	GetConfig() Config
 }
@@ -387,11 +643,18 @@ type PokeApi[T pokecache.Cache] struct {
 	BaseUrl string // e.g: https://pokeapi.co/api/v2
 	Config  Config
 	Cache   T
+	flights *flightGroup
 }
 
 func NewPokeApi[T pokecache.Cache](url string, cache T, opts ...Option) PokeApi[T] {
 	config := Config{
-		Limit: 20,
+		Limit:   20,
+		Timeout: 15 * time.Second,
+		Retry: RetryPolicy{
+			MaxRetries: 3,
+			BaseDelay:  250 * time.Millisecond,
+			MaxDelay:   5 * time.Second,
+		},
 	}
 
 	// apply any override option
@@ -403,6 +666,7 @@ func NewPokeApi[T pokecache.Cache](url string, cache T, opts ...Option) PokeApi[
 		BaseUrl: url,
 		Config:  config,
 		Cache:   cache,
+		flights: newFlightGroup(),
 	}
 }
 
@@ -414,84 +678,206 @@ func (api PokeApi[T]) GetConfig() Config {
 	return api.Config
 }
 
-func (api PokeApi[T]) GetPokemon(name string) (*Pokemon, error) {
+func (api PokeApi[T]) GetPokemon(ctx context.Context, name string) (*Pokemon, error) {
 	url := fmt.Sprintf("%s/pokemon/%s", api.BaseUrl, name)
-	data, exist := api.Cache.Get(url)
-	if exist {
-		return getResponse[Pokemon](data)
-	} else {
-		res, err := api.requestApi(url)
-		if err != nil {
-			return nil, err
-		}
-		response, err := getResponse[Pokemon](res)
-		if err != nil {
-			return nil, err
-		}
-		api.Cache.Add(url, res)
-		return response, nil
-	}
+	return fetchResource[Pokemon](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetPokemonSpecies(ctx context.Context, name string) (*PokemonSpecies, error) {
+	url := fmt.Sprintf("%s/pokemon-species/%s", api.BaseUrl, name)
+	return fetchResource[PokemonSpecies](ctx, api, url)
 }
 
-func (api PokeApi[T]) GetLocationAreaDetails(area string) (*LocationAreaDetailsResponse, error) {
+func (api PokeApi[T]) GetEvolutionChain(ctx context.Context, id int) (*EvolutionChain, error) {
+	url := fmt.Sprintf("%s/evolution-chain/%d", api.BaseUrl, id)
+	return fetchResource[EvolutionChain](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetMove(ctx context.Context, name string) (*Move, error) {
+	url := fmt.Sprintf("%s/move/%s", api.BaseUrl, name)
+	return fetchResource[Move](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetType(ctx context.Context, name string) (*Type, error) {
+	url := fmt.Sprintf("%s/type/%s", api.BaseUrl, name)
+	return fetchResource[Type](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetGrowthRate(ctx context.Context, name string) (*GrowthRate, error) {
+	url := fmt.Sprintf("%s/growth-rate/%s", api.BaseUrl, name)
+	return fetchResource[GrowthRate](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetNature(ctx context.Context, name string) (*Nature, error) {
+	url := fmt.Sprintf("%s/nature/%s", api.BaseUrl, name)
+	return fetchResource[Nature](ctx, api, url)
+}
+
+func (api PokeApi[T]) GetLocationAreaDetails(ctx context.Context, area string) (*LocationAreaDetailsResponse, error) {
 	url := fmt.Sprintf("%s/location-area/%s", api.BaseUrl, area)
-	data, exist := api.Cache.Get(url)
-	if exist {
-		return getResponse[LocationAreaDetailsResponse](data)
-	} else {
-		res, err := api.requestApi(url)
-		if err != nil {
-			return nil, err
-		}
-		response, err := getResponse[LocationAreaDetailsResponse](res)
-		if err != nil {
-			return nil, err
-		}
-		api.Cache.Add(url, res)
-		return response, nil
-	}
+	return fetchResource[LocationAreaDetailsResponse](ctx, api, url)
 }
 
-func (api PokeApi[T]) GetLocationArea(offset int) (*LocationAreaResponse, error) {
+func (api PokeApi[T]) GetLocationArea(ctx context.Context, offset int) (*LocationAreaResponse, error) {
 	url := fmt.Sprintf("%s/location-area?offset=%d&limit=%d", api.BaseUrl, offset, api.Config.Limit)
-	data, exist := api.Cache.Get(url)
-	if exist {
-		return getResponse[LocationAreaResponse](data)
-	} else {
-		res, err := api.requestApi(url)
-		if err != nil {
-			return nil, err
+	return fetchResource[LocationAreaResponse](ctx, api, url)
+}
+
+// GetResourceNames lists every name of a resource, e.g: "pokemon" or "location-area"
+func (api PokeApi[T]) GetResourceNames(ctx context.Context, resource string) ([]string, error) {
+	url := fmt.Sprintf("%s/%s?offset=0&limit=100000", api.BaseUrl, resource)
+	response, err := fetchResource[NamedResourceList](ctx, api, url)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(response.Results))
+	for _, result := range response.Results {
+		names = append(names, result.Name)
+	}
+	return names, nil
+}
+
+// fetchResource looks url up in the cache and falls back to the network,
+// caching the raw body only once it decodes into R. Concurrent misses for
+// the same url share a single request. A stale entry is revalidated with a
+// conditional request, in the background when serving stale is enabled.
+func fetchResource[R any, T pokecache.Cache](ctx context.Context, api PokeApi[T], url string) (*R, error) {
+	entry, exist := pokecache.GetEntry(api.Cache, url)
+	if exist && !entry.Stale {
+		return getResponse[R](url, entry.Val)
+	}
+	if exist && api.Config.StaleWhileRevalidate {
+		go api.revalidate(context.WithoutCancel(ctx), url, entry)
+		return getResponse[R](url, entry.Val)
+	}
+	res, err := api.coalescedRequest(ctx, url, entry.Meta)
+	if err != nil {
+		return nil, err
+	}
+	if res.NotModified {
+		pokecache.AddEntry(api.Cache, url, entry.Val, res.Meta)
+		return getResponse[R](url, entry.Val)
+	}
+	response, err := getResponse[R](url, res.Body)
+	if err != nil {
+		return nil, err
+	}
+	pokecache.AddEntry(api.Cache, url, res.Body, res.Meta)
+	return response, nil
+}
+
+// revalidate refreshes a stale entry, on failure the stale entry is kept as is
+func (api PokeApi[T]) revalidate(ctx context.Context, url string, entry pokecache.PokeEntry) {
+	res, err := api.coalescedRequest(ctx, url, entry.Meta)
+	if err != nil {
+		return
+	}
+	if res.NotModified {
+		pokecache.AddEntry(api.Cache, url, entry.Val, res.Meta)
+	} else if json.Valid(res.Body) {
+		pokecache.AddEntry(api.Cache, url, res.Body, res.Meta)
+	}
+}
+
+type apiResponse struct {
+	Body        []byte
+	Meta        pokecache.Metadata
+	NotModified bool
+}
+
+func (api PokeApi[T]) coalescedRequest(ctx context.Context, url string, meta pokecache.Metadata) (*apiResponse, error) {
+	if api.flights == nil {
+		return api.requestApi(ctx, url, meta)
+	}
+	// conditional and plain requests can't share a response
+	key := url
+	if meta != (pokecache.Metadata{}) {
+		key = fmt.Sprintf("%s\x00%s\x00%s", url, meta.ETag, meta.LastModified)
+	}
+	return api.flights.Do(ctx, key, func(ctx context.Context) (*apiResponse, error) {
+		return api.requestApi(ctx, url, meta)
+	})
+}
+
+func (api PokeApi[T]) requestApi(ctx context.Context, url string, meta pokecache.Metadata) (*apiResponse, error) {
+	for attempt := 0; ; attempt++ {
+		if api.Config.Limiter != nil {
+			if err := api.Config.Limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
 		}
-		response, err := getResponse[LocationAreaResponse](res)
+		res, body, err := api.doRequest(ctx, url, meta)
 		if err != nil {
 			return nil, err
 		}
-		api.Cache.Add(url, res)
-		return response, nil
+		if res.StatusCode == http.StatusNotModified && meta != (pokecache.Metadata{}) {
+			return &apiResponse{Meta: responseMetadata(res, meta), NotModified: true}, nil
+		}
+		if res.StatusCode > 299 {
+			if attempt < api.Config.Retry.MaxRetries && shouldRetry(res.StatusCode) {
+				if d, ok := api.Config.Retry.delay(attempt, res); ok {
+					if err := sleep(ctx, d); err != nil {
+						return nil, err
+					}
+					continue
+				}
+			}
+			return nil, &HTTPError{StatusCode: res.StatusCode, URL: url}
+		}
+		return &apiResponse{Body: body, Meta: responseMetadata(res, pokecache.Metadata{})}, nil
 	}
 }
 
-func (api PokeApi[T]) requestApi(url string) ([]byte, error) {
-	res, err := http.Get(url)
+// doRequest performs a single GET, bounded by the configured timeout
+func (api PokeApi[T]) doRequest(ctx context.Context, url string, meta pokecache.Metadata) (*http.Response, []byte, error) {
+	if api.Config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, api.Config.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	body, err := io.ReadAll(res.Body)
-	res.Body.Close()
-	if res.StatusCode > 299 {
-		return nil, errors.New(fmt.Sprintf("Response failed with status code: %d and\nbody: %s\n", res.StatusCode, body))
+	if meta.ETag != "" {
+		req.Header.Set("If-None-Match", meta.ETag)
+	}
+	if meta.LastModified != "" {
+		req.Header.Set("If-Modified-Since", meta.LastModified)
+	}
+	client := api.Config.Client
+	if client == nil {
+		client = http.DefaultClient
 	}
+	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil && res.StatusCode <= 299 {
+		return nil, nil, err
 	}
+	return res, body, nil
+}
 
-	return body, nil
+// responseMetadata reads the validators of res, keeping the previous ones
+// when a 304 does not repeat them
+func responseMetadata(res *http.Response, previous pokecache.Metadata) pokecache.Metadata {
+	meta := previous
+	if etag := res.Header.Get("ETag"); etag != "" {
+		meta.ETag = etag
+	}
+	if lastModified := res.Header.Get("Last-Modified"); lastModified != "" {
+		meta.LastModified = lastModified
+	}
+	return meta
 }
 
-func getResponse[T any](data []byte) (*T, error) {
+func getResponse[T any](url string, data []byte) (*T, error) {
 	var response T
 	if err := json.Unmarshal(data, &response); err != nil {
-		return nil, err
+		return nil, &DecodeError{URL: url, Err: err}
 	}
 	return &response, nil
 }
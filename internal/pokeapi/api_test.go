@@ -1,13 +1,19 @@
 package pokeapi_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/pokecache"
 )
 
 type MockCache struct {
@@ -33,6 +39,7 @@ func (c *MockCache) Stop() {
 
 func TestNewPokeApi(t *testing.T) {
 	// arrange
+	defaultRetry := pokeapi.RetryPolicy{MaxRetries: 3, BaseDelay: 250 * time.Millisecond, MaxDelay: 5 * time.Second}
 	baseUrl := "https://pokeapi.co/api/v2"
 	type ctr struct {
 		baseUrl string
@@ -52,7 +59,7 @@ func TestNewPokeApi(t *testing.T) {
 			},
 			expected: pokeapi.PokeApi[*MockCache]{
 				BaseUrl: baseUrl,
-				Config:  pokeapi.Config{Limit: 20},
+				Config:  pokeapi.Config{Limit: 20, Timeout: 15 * time.Second, Retry: defaultRetry},
 				Cache:   cache,
 			},
 		},
@@ -64,7 +71,7 @@ func TestNewPokeApi(t *testing.T) {
 			},
 			expected: pokeapi.PokeApi[*MockCache]{
 				BaseUrl: baseUrl,
-				Config:  pokeapi.Config{Limit: 10},
+				Config:  pokeapi.Config{Limit: 10, Timeout: 15 * time.Second, Retry: defaultRetry},
 				Cache:   cache,
 			},
 		},
@@ -75,7 +82,7 @@ func TestNewPokeApi(t *testing.T) {
 		api := pokeapi.NewPokeApi(c.actual.baseUrl, c.actual.cache, c.actual.opts...)
 
 		// assert
-		if c.expected != api {
+		if c.expected.BaseUrl != api.BaseUrl || c.expected.Config != api.Config || c.expected.Cache != api.Cache {
 			t.Errorf("Invalid PokeApi created for params: %v", c.actual)
 			t.Fail()
 		}
@@ -95,7 +102,7 @@ func TestGetLocationAreaFromApi(t *testing.T) {
 	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithLimit(20))
 
 	// act
-	r, err := api.GetLocationArea(0)
+	r, err := api.GetLocationArea(context.Background(), 0)
 
 	// assert
 	if err != nil || r.Count != 1 {
@@ -119,7 +126,7 @@ func TestGetLocationAreaFromCache(t *testing.T) {
 	cache.Add(fmt.Sprintf("%s/location-area?offset=%d&limit=%d", api.BaseUrl, 0, api.Config.Limit), cacheData)
 
 	// act
-	r, err := api.GetLocationArea(0)
+	r, err := api.GetLocationArea(context.Background(), 0)
 
 	// assert
 	if err != nil || r.Count != 10 {
@@ -141,7 +148,7 @@ func TestGetLocationAreaDetailsFromApi(t *testing.T) {
 	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithLimit(20))
 
 	// act
-	d, err := api.GetLocationAreaDetails(name)
+	d, err := api.GetLocationAreaDetails(context.Background(), name)
 
 	// assert
 	if err != nil || d.Name != name {
@@ -167,7 +174,7 @@ func TestGetLocationAreaDetailsFromCache(t *testing.T) {
 	cache.Add(fmt.Sprintf("%s/location-area/%s", api.BaseUrl, cacheName), cacheData)
 
 	// act
-	d, err := api.GetLocationAreaDetails(cacheName)
+	d, err := api.GetLocationAreaDetails(context.Background(), cacheName)
 
 	// assert
 	if err != nil || d.Name != cacheName {
@@ -187,7 +194,7 @@ func TestGetPokemonFromApi(t *testing.T) {
 
 	api := pokeapi.NewPokeApi(ts.URL, cache)
 
-	p, err := api.GetPokemon(name)
+	p, err := api.GetPokemon(context.Background(), name)
 	if err != nil || p.Name != name {
 		t.Errorf("unexpected result: %v, err: %v", p, err)
 	}
@@ -209,7 +216,7 @@ func TestGetPokemonFromCache(t *testing.T) {
 	cacheData, _ := json.Marshal(pokeapi.Pokemon{Name: cacheName})
 	cache.Add(fmt.Sprintf("%s/pokemon/%s", api.BaseUrl, cacheName), cacheData)
 
-	p, err := api.GetPokemon(cacheName)
+	p, err := api.GetPokemon(context.Background(), cacheName)
 	if err != nil || p.Name != cacheName {
 		t.Errorf("unexpected result: %v, err: %v", p, err)
 	}
@@ -223,23 +230,23 @@ func TestApiHttpStatusError(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	api := pokeapi.NewPokeApi(ts.URL, cache)
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(0, 0, 0))
 
 	t.Run("returns GetPokemon error", func(t *testing.T) {
-		_, err := api.GetPokemon("pikachu")
+		_, err := api.GetPokemon(context.Background(), "pikachu")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 	t.Run("returns GetLocationArea error", func(t *testing.T) {
-		_, err := api.GetLocationArea(0)
+		_, err := api.GetLocationArea(context.Background(), 0)
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 
 	t.Run("returns GetLocationAreaDetails error", func(t *testing.T) {
-		_, err := api.GetLocationAreaDetails("canalave-city-area")
+		_, err := api.GetLocationAreaDetails(context.Background(), "canalave-city-area")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
@@ -257,20 +264,20 @@ func TestApiParseBodyError(t *testing.T) {
 	api := pokeapi.NewPokeApi(ts.URL, cache)
 
 	t.Run("returns GetPokemon error", func(t *testing.T) {
-		_, err := api.GetPokemon("pikachu")
+		_, err := api.GetPokemon(context.Background(), "pikachu")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 	t.Run("returns GetLocationArea error", func(t *testing.T) {
-		_, err := api.GetLocationArea(0)
+		_, err := api.GetLocationArea(context.Background(), 0)
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 
 	t.Run("returns GetLocationAreaDetails error", func(t *testing.T) {
-		_, err := api.GetLocationAreaDetails("canalave-city-area")
+		_, err := api.GetLocationAreaDetails(context.Background(), "canalave-city-area")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
@@ -291,20 +298,20 @@ func TestApiHttpReadBodyError(t *testing.T) {
 	api := pokeapi.NewPokeApi(ts.URL, cache)
 
 	t.Run("returns GetPokemon error", func(t *testing.T) {
-		_, err := api.GetPokemon("pikachu")
+		_, err := api.GetPokemon(context.Background(), "pikachu")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 	t.Run("returns GetLocationArea error", func(t *testing.T) {
-		_, err := api.GetLocationArea(0)
+		_, err := api.GetLocationArea(context.Background(), 0)
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 
 	t.Run("returns GetLocationAreaDetails error", func(t *testing.T) {
-		_, err := api.GetLocationAreaDetails("canalave-city-area")
+		_, err := api.GetLocationAreaDetails(context.Background(), "canalave-city-area")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
@@ -322,22 +329,716 @@ func TestApiNetworkError(t *testing.T) {
 	api := pokeapi.NewPokeApi("http://invalid.localhost/", cache)
 
 	t.Run("returns GetPokemon error", func(t *testing.T) {
-		_, err := api.GetPokemon("pikachu")
+		_, err := api.GetPokemon(context.Background(), "pikachu")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 	t.Run("returns GetLocationArea error", func(t *testing.T) {
-		_, err := api.GetLocationArea(0)
+		_, err := api.GetLocationArea(context.Background(), 0)
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 
 	t.Run("returns GetLocationAreaDetails error", func(t *testing.T) {
-		_, err := api.GetLocationAreaDetails("canalave-city-area")
+		_, err := api.GetLocationAreaDetails(context.Background(), "canalave-city-area")
 		if err == nil || len(cache.store) > 0 {
 			t.Errorf("expected error and no item added to cache: %v, err: %v", len(cache.store), err)
 		}
 	})
 }
+
+func TestGetPokemonSpeciesFromApi(t *testing.T) {
+	name := "eevee"
+	data, _ := json.Marshal(pokeapi.PokemonSpecies{Name: name, CaptureRate: 45})
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	s, err := api.GetPokemonSpecies(context.Background(), name)
+	if err != nil || s.Name != name || s.CaptureRate != 45 {
+		t.Errorf("unexpected result: %v, err: %v", s, err)
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%s/pokemon-species/%s", api.BaseUrl, name)); !ok {
+		t.Errorf("expected species to be cached")
+	}
+}
+
+func TestGetMoveFromApi(t *testing.T) {
+	cache := NewMockCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":84,"name":"thunder-shock","power":40,"accuracy":100,"pp":30,"priority":0,
+			"damage_class":{"name":"special"},"type":{"name":"electric"}}`))
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+	m, err := api.GetMove(context.Background(), "thunder-shock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "thunder-shock" || *m.Power != 40 || *m.Accuracy != 100 || m.PP != 30 || m.DamageClass.Name != "special" || m.Type.Name != "electric" {
+		t.Errorf("unexpected move: %+v", m)
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%s/move/thunder-shock", api.BaseUrl)); !ok {
+		t.Errorf("expected move to be cached")
+	}
+}
+
+func TestGetTypeFromApi(t *testing.T) {
+	cache := NewMockCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":13,"name":"electric","damage_relations":{
+			"double_damage_to":[{"name":"water"},{"name":"flying"}],"half_damage_to":[{"name":"electric"}],
+			"no_damage_to":[{"name":"ground"}],"double_damage_from":[{"name":"ground"}]}}`))
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+	typ, err := api.GetType(context.Background(), "electric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relations := typ.DamageRelations
+	if typ.Name != "electric" || len(relations.DoubleDamageTo) != 2 || relations.NoDamageTo[0].Name != "ground" || relations.DoubleDamageFrom[0].Name != "ground" {
+		t.Errorf("unexpected type: %+v", typ)
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%s/type/electric", api.BaseUrl)); !ok {
+		t.Errorf("expected type to be cached")
+	}
+}
+
+func TestGetGrowthRateFromApi(t *testing.T) {
+	cache := NewMockCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":4,"name":"medium-slow","formula":"...","levels":[{"level":1,"experience":0},{"level":2,"experience":9}]}`))
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+	rate, err := api.GetGrowthRate(context.Background(), "medium-slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate.Name != "medium-slow" || len(rate.Levels) != 2 || rate.Levels[1].Level != 2 || rate.Levels[1].Experience != 9 {
+		t.Errorf("unexpected growth rate: %+v", rate)
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%s/growth-rate/medium-slow", api.BaseUrl)); !ok {
+		t.Errorf("expected growth rate to be cached")
+	}
+}
+
+func TestGetNatureFromApi(t *testing.T) {
+	cache := NewMockCache()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/nature/hardy" {
+			w.Write([]byte(`{"id":1,"name":"hardy","decreased_stat":null,"increased_stat":null}`))
+			return
+		}
+		w.Write([]byte(`{"id":3,"name":"adamant","decreased_stat":{"name":"special-attack"},"increased_stat":{"name":"attack"}}`))
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+	nature, err := api.GetNature(context.Background(), "adamant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nature.Name != "adamant" || nature.IncreasedStat == nil || nature.IncreasedStat.Name != "attack" || nature.DecreasedStat.Name != "special-attack" {
+		t.Errorf("unexpected nature: %+v", nature)
+	}
+	if _, ok := cache.Get(fmt.Sprintf("%s/nature/adamant", api.BaseUrl)); !ok {
+		t.Errorf("expected nature to be cached")
+	}
+
+	nature, err = api.GetNature(context.Background(), "hardy")
+	if err != nil || nature.IncreasedStat != nil || nature.DecreasedStat != nil {
+		t.Errorf("GetNature(hardy) = %+v, %v; want a neutral nature", nature, err)
+	}
+}
+
+func TestGetEvolutionChainFromCache(t *testing.T) {
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	cacheData := []byte(`{"id":1,"chain":{"species":{"name":"bulbasaur"},"evolves_to":[{"species":{"name":"ivysaur"},"evolution_details":[{"min_level":16,"trigger":{"name":"level-up"}}]}]}}`)
+	cache.Add(fmt.Sprintf("%s/evolution-chain/%d", api.BaseUrl, 1), cacheData)
+
+	c, err := api.GetEvolutionChain(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if c.Chain.Species.Name != "bulbasaur" || len(c.Chain.EvolvesTo) != 1 {
+		t.Fatalf("unexpected chain: %v", c)
+	}
+	next := c.Chain.EvolvesTo[0]
+	if next.Species.Name != "ivysaur" || *next.EvolutionDetails[0].MinLevel != 16 {
+		t.Errorf("unexpected evolution: %v", next)
+	}
+}
+
+func TestApiRequestTimeout(t *testing.T) {
+	cache := NewMockCache()
+	release := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithTimeout(10*time.Millisecond))
+
+	_, err := api.GetPokemon(context.Background(), "pikachu")
+	if !errors.Is(err, context.DeadlineExceeded) || len(cache.store) > 0 {
+		t.Errorf("expected deadline exceeded and no item added to cache: %v, err: %v", len(cache.store), err)
+	}
+}
+
+func TestApiRequestCancelled(t *testing.T) {
+	cache := NewMockCache()
+	started := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err := api.GetLocationAreaDetails(ctx, "canalave-city-area")
+	if !errors.Is(err, context.Canceled) || len(cache.store) > 0 {
+		t.Errorf("expected cancellation and no item added to cache: %v, err: %v", len(cache.store), err)
+	}
+}
+
+func TestApiRetryTransientStatus(t *testing.T) {
+	data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+	cache := NewMockCache()
+	var calls atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		case 2:
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		default:
+			w.Write(data)
+		}
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(3, time.Millisecond, 5*time.Millisecond))
+
+	p, err := api.GetPokemon(context.Background(), "pikachu")
+	if err != nil || p.Name != "pikachu" || calls.Load() != 3 {
+		t.Errorf("unexpected result: %v, calls: %d, err: %v", p, calls.Load(), err)
+	}
+}
+
+func TestApiRetryGivesUp(t *testing.T) {
+	cache := NewMockCache()
+	var calls atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(2, time.Millisecond, time.Millisecond))
+
+	_, err := api.GetPokemon(context.Background(), "pikachu")
+	if err == nil || calls.Load() != 3 {
+		t.Errorf("expected error after 3 attempts, calls: %d, err: %v", calls.Load(), err)
+	}
+}
+
+func TestApiRetryDoesNotRetryClientErrors(t *testing.T) {
+	cache := NewMockCache()
+	var calls atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(3, time.Millisecond, time.Millisecond))
+
+	_, err := api.GetPokemon(context.Background(), "pikahcu")
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("expected a single attempt, calls: %d, err: %v", calls.Load(), err)
+	}
+}
+
+func TestApiRetryHonoursRetryAfter(t *testing.T) {
+	data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+	cache := NewMockCache()
+	var calls atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(1, time.Millisecond, 5*time.Second))
+
+	start := time.Now()
+	_, err := api.GetPokemon(context.Background(), "pikachu")
+	if elapsed := time.Since(start); err != nil || elapsed < time.Second {
+		t.Errorf("expected to wait for Retry-After, elapsed: %v, err: %v", elapsed, err)
+	}
+}
+
+func TestApiRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	cache := NewMockCache()
+	var calls atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRetry(3, time.Millisecond, 5*time.Second))
+
+	start := time.Now()
+	_, err := api.GetPokemon(context.Background(), "pikachu")
+	var httpErr *pokeapi.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("expected the 503 without retrying, calls: %d, err: %v", calls.Load(), err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected not to wait for Retry-After, elapsed: %v", elapsed)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestApiCustomTransport(t *testing.T) {
+	data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "pokedexcli-test" {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	transport := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r.Header.Set("User-Agent", "pokedexcli-test")
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithTransport(transport))
+
+	p, err := api.GetPokemon(context.Background(), "pikachu")
+	if err != nil || p.Name != "pikachu" {
+		t.Errorf("unexpected result: %v, err: %v", p, err)
+	}
+}
+
+func TestApiRateLimit(t *testing.T) {
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+
+	// burst of 1 token refilled every 50ms: 3 requests need at least 100ms
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithRateLimit(20, 1))
+
+	start := time.Now()
+	for _, name := range []string{"bulbasaur", "ivysaur", "venusaur"} {
+		if _, err := api.GetPokemon(context.Background(), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected requests to be rate limited, elapsed: %v", elapsed)
+	}
+}
+
+func TestNewRateLimiterRejectsNonPositive(t *testing.T) {
+	for _, c := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {-1, 1}, {1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRateLimiter(%v, %d) should panic", c.rate, c.burst)
+				}
+			}()
+			pokeapi.NewRateLimiter(c.rate, c.burst)
+		}()
+	}
+}
+
+func TestApiTypedErrors(t *testing.T) {
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pokemon/pikahcu":
+			http.Error(w, "Not Found", http.StatusNotFound)
+		case "/pokemon/pikachu":
+			w.Write([]byte("{ invalid json "))
+		default:
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := api.GetPokemon(context.Background(), "pikahcu")
+		var httpErr *pokeapi.HTTPError
+		if !errors.Is(err, pokeapi.ErrNotFound) || !errors.As(err, &httpErr) {
+			t.Fatalf("expected ErrNotFound HTTPError, got: %v", err)
+		}
+		if httpErr.StatusCode != http.StatusNotFound || httpErr.URL != ts.URL+"/pokemon/pikahcu" {
+			t.Errorf("unexpected HTTPError: %+v", httpErr)
+		}
+	})
+
+	t.Run("other status", func(t *testing.T) {
+		_, err := api.GetLocationAreaDetails(context.Background(), "canalave-city-area")
+		var httpErr *pokeapi.HTTPError
+		if errors.Is(err, pokeapi.ErrNotFound) || !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+			t.Errorf("expected forbidden HTTPError, got: %v", err)
+		}
+	})
+
+	t.Run("bad json", func(t *testing.T) {
+		_, err := api.GetPokemon(context.Background(), "pikachu")
+		var decodeErr *pokeapi.DecodeError
+		var syntaxErr *json.SyntaxError
+		if !errors.As(err, &decodeErr) || !errors.As(err, &syntaxErr) {
+			t.Errorf("expected DecodeError wrapping json error, got: %v", err)
+		}
+	})
+}
+
+func TestGetResourceNames(t *testing.T) {
+	cache := NewMockCache()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pokemon" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"count":2,"results":[{"name":"bulbasaur"},{"name":"ivysaur"}]}`))
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	names, err := api.GetResourceNames(context.Background(), "pokemon")
+	if err != nil || len(names) != 2 || names[0] != "bulbasaur" || names[1] != "ivysaur" {
+		t.Errorf("unexpected names: %v, err: %v", names, err)
+	}
+}
+
+// countingCache records cache misses so tests know when every caller is about to hit the network
+type countingCache struct {
+	*MockCache
+	mux    sync.Mutex
+	misses sync.WaitGroup
+}
+
+func (c *countingCache) Get(key string) ([]byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	val, ok := c.MockCache.Get(key)
+	if !ok {
+		c.misses.Done()
+	}
+	return val, ok
+}
+
+func (c *countingCache) Add(key string, val []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.MockCache.Add(key, val)
+}
+
+func TestApiCoalescesConcurrentMisses(t *testing.T) {
+	const callers = 10
+	cases := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		check   func(t *testing.T, p *pokeapi.Pokemon, err error)
+	}{
+		{
+			name: "shares result",
+			handler: func(w http.ResponseWriter) {
+				data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+				w.Write(data)
+			},
+			check: func(t *testing.T, p *pokeapi.Pokemon, err error) {
+				if err != nil || p.Name != "pikachu" {
+					t.Errorf("unexpected result: %v, err: %v", p, err)
+				}
+			},
+		},
+		{
+			name: "shares error",
+			handler: func(w http.ResponseWriter) {
+				http.Error(w, "Not Found", http.StatusNotFound)
+			},
+			check: func(t *testing.T, p *pokeapi.Pokemon, err error) {
+				if !errors.Is(err, pokeapi.ErrNotFound) {
+					t.Errorf("expected not found, got: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cache := &countingCache{MockCache: NewMockCache()}
+			cache.misses.Add(callers)
+			release := make(chan struct{})
+			var requests atomic.Int32
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				<-release
+				c.handler(w)
+			}))
+			defer ts.Close()
+
+			api := pokeapi.NewPokeApi(ts.URL, cache)
+
+			var wg sync.WaitGroup
+			for i := 0; i < callers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					p, err := api.GetPokemon(context.Background(), "pikachu")
+					c.check(t, p, err)
+				}()
+			}
+
+			// every caller missed the cache, give them a moment to join the flight
+			cache.misses.Wait()
+			time.Sleep(20 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if n := requests.Load(); n != 1 {
+				t.Errorf("expected a single request, got: %d", n)
+			}
+		})
+	}
+}
+
+func TestApiCoalescedRequestSurvivesCallerCancel(t *testing.T) {
+	data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+	cache := &countingCache{MockCache: NewMockCache()}
+	cache.misses.Add(2)
+	release := make(chan struct{})
+	var requests atomic.Int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := api.GetPokemon(ctx, "pikachu")
+		first <- err
+	}()
+	second := make(chan error, 1)
+	go func() {
+		_, err := api.GetPokemon(context.Background(), "pikachu")
+		second <- err
+	}()
+
+	cache.misses.Wait()
+	time.Sleep(20 * time.Millisecond)
+
+	// the first caller leaves, the request keeps going for the second one
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected first caller to be cancelled, got: %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected second caller to get the result, got: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected a single request, got: %d", n)
+	}
+}
+
+// revalidatingServer serves body with an ETag and answers 304 to matching If-None-Match
+type revalidatingServer struct {
+	mux         sync.Mutex
+	etag        string
+	body        []byte
+	full        int
+	notModified int
+}
+
+func (s *revalidatingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	w.Header().Set("ETag", s.etag)
+	if r.Header.Get("If-None-Match") == s.etag {
+		s.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.full++
+	w.Write(s.body)
+}
+
+func (s *revalidatingServer) counts() (int, int) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.full, s.notModified
+}
+
+// fakeClock lets tests expire cache entries without sleeping
+type fakeClock struct {
+	mux sync.Mutex
+	t   time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestApiConditionalRevalidation(t *testing.T) {
+	const ttl = time.Hour
+	clock := &fakeClock{t: time.Now()}
+	data, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu"})
+	server := &revalidatingServer{etag: `"v1"`, body: data}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cache := pokecache.NewPokeCache(ttl, pokecache.WithStaleTTL(time.Hour), pokecache.WithClock(clock.now))
+	defer cache.Stop()
+	api := pokeapi.NewPokeApi(ts.URL, cache)
+
+	if _, err := api.GetPokemon(context.Background(), "pikachu"); err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := cache.GetEntry(ts.URL + "/pokemon/pikachu")
+	if entry.Meta.ETag != `"v1"` {
+		t.Errorf("expected ETag to be stored, got: %+v", entry.Meta)
+	}
+
+	clock.advance(ttl)
+
+	p, err := api.GetPokemon(context.Background(), "pikachu")
+	if err != nil || p.Name != "pikachu" {
+		t.Fatalf("unexpected result: %v, err: %v", p, err)
+	}
+	if full, notModified := server.counts(); full != 1 || notModified != 1 {
+		t.Errorf("expected a single full response and a 304, got: %d full, %d not modified", full, notModified)
+	}
+	if _, ok := cache.Get(ts.URL + "/pokemon/pikachu"); !ok {
+		t.Errorf("expected 304 to refresh the cached entry")
+	}
+}
+
+func TestApiStaleWhileRevalidate(t *testing.T) {
+	const ttl = time.Hour
+	clock := &fakeClock{t: time.Now()}
+	v1, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu", Weight: 60})
+	v2, _ := json.Marshal(pokeapi.Pokemon{Name: "pikachu", Weight: 61})
+	server := &revalidatingServer{etag: `"v1"`, body: v1}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cache := pokecache.NewPokeCache(ttl, pokecache.WithStaleTTL(time.Hour), pokecache.WithClock(clock.now))
+	defer cache.Stop()
+	api := pokeapi.NewPokeApi(ts.URL, cache, pokeapi.WithStaleWhileRevalidate())
+
+	if _, err := api.GetPokemon(context.Background(), "pikachu"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mux.Lock()
+	server.etag, server.body = `"v2"`, v2
+	server.mux.Unlock()
+	clock.advance(ttl)
+
+	// the stale entry is served right away
+	p, err := api.GetPokemon(context.Background(), "pikachu")
+	if err != nil || p.Weight != 60 {
+		t.Fatalf("expected stale pokemon, got: %v, err: %v", p, err)
+	}
+
+	// and refreshed in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, _ := cache.GetEntry(ts.URL + "/pokemon/pikachu")
+		if entry.Meta.ETag == `"v2"` {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("entry was not revalidated in the background: %+v", entry.Meta)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p, err = api.GetPokemon(context.Background(), "pikachu")
+	if err != nil || p.Weight != 61 {
+		t.Errorf("expected refreshed pokemon, got: %v, err: %v", p, err)
+	}
+}
@@ -0,0 +1,38 @@
+package pokeapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotFound matches (via errors.Is) any request the api answered with 404
+var ErrNotFound = errors.New("pokeapi: resource not found")
+
+// HTTPError is returned when the api answers with a non 2xx status code
+type HTTPError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("pokeapi: GET %s failed with status code %d", e.URL, e.StatusCode)
+}
+
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// DecodeError is returned when a response body (fresh or cached) is not valid json
+type DecodeError struct {
+	URL string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("pokeapi: invalid response from %s: %v", e.URL, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
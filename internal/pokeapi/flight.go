@@ -0,0 +1,72 @@
+package pokeapi
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces concurrent calls for the same key into a single
+// execution whose result, error included, is shared by every caller
+type flightGroup struct {
+	mux   sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done    chan struct{}
+	val     *apiResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{calls: map[string]*flight{}}
+}
+
+// Do runs fn once per key at a time. fn gets a context that outlives any single
+// caller and is only cancelled once every caller waiting on it has given up
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(context.Context) (*apiResponse, error)) (*apiResponse, error) {
+	g.mux.Lock()
+	f, ok := g.calls[key]
+	if ok {
+		f.waiters++
+		g.mux.Unlock()
+	} else {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = f
+		g.mux.Unlock()
+
+		go func() {
+			f.val, f.err = fn(fctx)
+			g.mux.Lock()
+			g.forget(key, f)
+			g.mux.Unlock()
+			cancel()
+			close(f.done)
+		}()
+	}
+
+	select {
+	case <-f.done:
+		return f.val, f.err
+	case <-ctx.Done():
+		g.mux.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// nobody is left to use the result, new callers start over
+			g.forget(key, f)
+			f.cancel()
+		}
+		g.mux.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// forget must be called holding the lock
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.calls[key] == f {
+		delete(g.calls, key)
+	}
+}
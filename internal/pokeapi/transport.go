@@ -0,0 +1,134 @@
+package pokeapi
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// shouldRetry reports whether a response status is worth trying again:
+// rate limited (429) or any server side failure (5xx)
+func shouldRetry(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// delay returns how long to wait before retry number attempt (starting at 0).
+// A Retry-After header wins over the exponential backoff, and is not worth
+// waiting for when longer than MaxDelay: ok is false and the caller gives up.
+// Otherwise it uses "full jitter": a random duration in
+// [0, min(MaxDelay, BaseDelay*2^attempt))
+func (p RetryPolicy) delay(attempt int, res *http.Response) (d time.Duration, ok bool) {
+	if res != nil {
+		if d, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			return d, p.MaxDelay <= 0 || d <= p.MaxDelay
+		}
+	}
+	backoff := p.BaseDelay << attempt
+	if backoff <= 0 || (p.MaxDelay > 0 && backoff > p.MaxDelay) {
+		backoff = p.MaxDelay
+	}
+	if backoff <= 0 {
+		return 0, true
+	}
+	return rand.N(backoff), true
+}
+
+// retryAfter parses a Retry-After header value, either delay-seconds or an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := date.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done, whichever comes first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RateLimiter is a token bucket: it holds up to burst tokens and refills
+// at rate tokens per second, every request takes one token
+type RateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mux    sync.Mutex
+}
+
+// NewRateLimiter panics unless rate and burst are positive, Wait would block
+// forever otherwise
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if rate <= 0 || burst < 1 {
+		panic(fmt.Sprintf("pokeapi: invalid rate limit of %v requests per second with bursts of %d", rate, burst))
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		d := l.reserve(time.Now())
+		if d == 0 {
+			return nil
+		}
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if there is one, otherwise returns how long
+// until the next token is refilled
+func (l *RateLimiter) reserve(now time.Time) time.Duration {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = min(l.burst, l.tokens+elapsed*l.rate)
+		l.last = now
+	}
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
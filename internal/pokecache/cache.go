@@ -1,6 +1,7 @@
 package pokecache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
@@ -10,9 +11,17 @@ type Entry interface {
 	GetVal() []byte
 }
 
+// Metadata holds the http validators used to revalidate an entry
+type Metadata struct {
+	ETag         string
+	LastModified string
+}
+
 type PokeEntry struct {
 	CreatedAt time.Time
 	Val       []byte
+	Meta      Metadata
+	Stale     bool // set on lookup once the entry outlived its ttl
 }
 
 func (e PokeEntry) Compare(t time.Time) int {
@@ -29,27 +38,161 @@ type Cache interface {
 	Stop()
 }
 
+// MetaCache is a Cache that also keeps response validators and hands out
+// expired (stale) entries, so they can be revalidated instead of refetched
+type MetaCache interface {
+	Cache
+	GetEntry(key string) (PokeEntry, bool)
+	AddEntry(key string, val []byte, meta Metadata)
+}
+
+// GetEntry looks key up in any Cache, plain caches only ever return fresh entries
+func GetEntry(c Cache, key string) (PokeEntry, bool) {
+	if mc, ok := c.(MetaCache); ok {
+		return mc.GetEntry(key)
+	}
+	val, ok := c.Get(key)
+	return PokeEntry{Val: val}, ok
+}
+
+// AddEntry stores val in any Cache, the metadata is dropped by plain caches
+func AddEntry(c Cache, key string, val []byte, meta Metadata) {
+	if mc, ok := c.(MetaCache); ok {
+		mc.AddEntry(key, val, meta)
+	} else {
+		c.Add(key, val)
+	}
+}
+
 type PokeCache struct {
-	items map[string]PokeEntry
-	done  chan bool
-	wg    sync.WaitGroup
-	mux   *sync.RWMutex
+	items    map[string]*list.Element
+	order    *list.List // most recently used at the front
+	bytes    int
+	maxItems int // zero means unbounded
+	maxBytes int // zero means unbounded
+	ttl      time.Duration
+	staleFor time.Duration // how long expired entries are kept for revalidation
+	now      func() time.Time
+	stats    CacheStats
+	done     chan bool
+	wg       sync.WaitGroup
+	mux      *sync.RWMutex
+}
+
+type lruItem struct {
+	key   string
+	entry PokeEntry
+}
+
+func (i *lruItem) size() int {
+	return len(i.key) + len(i.entry.Val)
+}
+
+type CacheStats struct {
+	Hits      int
+	Misses    int
+	Evictions int
+	Entries   int
+	Bytes     int
+}
+
+type Option interface {
+	apply(cache *PokeCache)
+}
+
+type maxEntriesOption int
+
+func (m maxEntriesOption) apply(cache *PokeCache) {
+	cache.maxItems = int(m)
+}
+
+// WithMaxEntries bounds the number of entries, evicting the least recently used
+func WithMaxEntries(entries int) maxEntriesOption {
+	return maxEntriesOption(entries)
+}
+
+type maxBytesOption int
+
+func (m maxBytesOption) apply(cache *PokeCache) {
+	cache.maxBytes = int(m)
+}
+
+func (m maxBytesOption) applyDisk(cache *DiskCache) {
+	cache.maxBytes = int(m)
+}
+
+// WithMaxBytes bounds the size of keys plus values, evicting the least recently
+// used. A DiskCache is bounded by the size of its files, evicting the oldest on
+// start.
+func WithMaxBytes(bytes int) maxBytesOption {
+	return maxBytesOption(bytes)
+}
+
+type staleOption time.Duration
+
+func (s staleOption) apply(cache *PokeCache) {
+	cache.staleFor = time.Duration(s)
+}
+
+// WithStaleTTL keeps expired entries around for d so they can be served
+// stale or revalidated, Get keeps ignoring them
+func WithStaleTTL(d time.Duration) staleOption {
+	return staleOption(d)
 }
 
-func NewPokeCache(interval time.Duration) *PokeCache {
+type clockOption func() time.Time
+
+func (c clockOption) apply(cache *PokeCache) {
+	cache.now = c
+}
+
+func (c clockOption) applyDisk(cache *DiskCache) {
+	cache.now = c
+}
+
+// WithClock replaces time.Now when stamping and expiring entries
+func WithClock(now func() time.Time) clockOption {
+	return clockOption(now)
+}
+
+func NewPokeCache(interval time.Duration, opts ...Option) *PokeCache {
 	cache := &PokeCache{
-		items: make(map[string]PokeEntry),
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		ttl:   interval,
+		now:   time.Now,
 		done:  make(chan bool),
 		mux:   &sync.RWMutex{},
 	}
+	for _, opt := range opts {
+		opt.apply(cache)
+	}
 	cache.wg.Add(1)
 	go cache.reapLoop(interval)
 	return cache
 }
 
 func (c *PokeCache) Add(key string, val []byte) {
+	c.AddEntry(key, val, Metadata{})
+}
+
+func (c *PokeCache) AddEntry(key string, val []byte, meta Metadata) {
 	withWriteLock(c.mux, func() {
-		c.items[key] = PokeEntry{time.Now(), val}
+		item := &lruItem{key, PokeEntry{CreatedAt: c.now(), Val: val, Meta: meta}}
+		if c.maxBytes > 0 && item.size() > c.maxBytes {
+			// would evict everything and still not fit
+			c.remove(key)
+			return
+		}
+		if el, ok := c.items[key]; ok {
+			c.bytes += item.size() - el.Value.(*lruItem).size()
+			el.Value = item
+			c.order.MoveToFront(el)
+		} else {
+			c.items[key] = c.order.PushFront(item)
+			c.bytes += item.size()
+		}
+		c.evict()
 	})
 }
 
@@ -60,22 +203,54 @@ type GetResult struct {
 }
 
 func (c *PokeCache) Get(key string) ([]byte, bool) {
-	r := withReadLock(c.mux, func() GetResult {
-		entry, ok := c.items[key]
-		return GetResult{entry, ok}
+	entry, ok := c.GetEntry(key)
+	if !ok || entry.Stale {
+		return nil, false
+	}
+	return entry.GetVal(), true
+}
+
+func (c *PokeCache) GetEntry(key string) (PokeEntry, bool) {
+	// a hit reorders the lru list so even reads need the write lock
+	var r GetResult
+	withWriteLock(c.mux, func() {
+		el, ok := c.items[key]
+		if !ok {
+			c.stats.Misses++
+			return
+		}
+		entry := el.Value.(*lruItem).entry
+		entry.Stale = entry.Compare(c.now().Add(-c.ttl)) <= 0
+		if entry.Stale {
+			c.stats.Misses++
+		} else {
+			c.stats.Hits++
+		}
+		c.order.MoveToFront(el)
+		r = GetResult{entry, true}
 	})
 	if r.ok {
-		return r.entry.GetVal(), true
-	} else {
-		return nil, false
+		return r.entry.(PokeEntry), true
 	}
+	return PokeEntry{}, false
+}
+
+func (c *PokeCache) Stats() CacheStats {
+	return withReadLock(c.mux, func() CacheStats {
+		stats := c.stats
+		stats.Entries = len(c.items)
+		stats.Bytes = c.bytes
+		return stats
+	})
 }
 
 func (c *PokeCache) Stop() {
 	close(c.done)
 	c.wg.Wait()
 	withWriteLock(c.mux, func() {
-		c.items = map[string]PokeEntry{}
+		c.items = map[string]*list.Element{}
+		c.order.Init()
+		c.bytes = 0
 	})
 }
 
@@ -88,8 +263,8 @@ func (c *PokeCache) reapLoop(interval time.Duration) {
 		select {
 		case <-c.done:
 			return
-		case t := <-ticker.C:
-			threshold := t.Add(-1 * interval)
+		case <-ticker.C:
+			threshold := c.now().Add(-1 * (interval + c.staleFor))
 			c.cleanCache(threshold)
 		}
 	}
@@ -97,14 +272,36 @@ func (c *PokeCache) reapLoop(interval time.Duration) {
 
 func (c *PokeCache) cleanCache(t time.Time) {
 	withWriteLock(c.mux, func() {
-		for key, entry := range c.items {
-			if entry.Compare(t) <= 0 {
-				delete(c.items, key)
+		for key, el := range c.items {
+			if el.Value.(*lruItem).entry.Compare(t) <= 0 {
+				c.remove(key)
 			}
 		}
 	})
 }
 
+// evict drops least recently used entries until both budgets are met,
+// it must be called holding the write lock
+func (c *PokeCache) evict() {
+	for (c.maxItems > 0 && len(c.items) > c.maxItems) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		oldest := c.order.Back()
+		if oldest == nil {
+			return
+		}
+		c.remove(oldest.Value.(*lruItem).key)
+		c.stats.Evictions++
+	}
+}
+
+// remove must be called holding the write lock
+func (c *PokeCache) remove(key string) {
+	if el, ok := c.items[key]; ok {
+		c.order.Remove(el)
+		c.bytes -= el.Value.(*lruItem).size()
+		delete(c.items, key)
+	}
+}
+
 func withReadLock[T any](mux *sync.RWMutex, f func() T) T {
 	mux.RLock()
 	defer mux.RUnlock()
@@ -2,6 +2,7 @@ package pokecache_test
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -61,3 +62,168 @@ func TestReapLoop(t *testing.T) {
 		return
 	}
 }
+
+func TestLRUMaxEntriesEvictionOrder(t *testing.T) {
+	cache := pokecache.NewPokeCache(time.Hour, pokecache.WithMaxEntries(3))
+	defer cache.Stop()
+
+	cache.Add("a", []byte("1"))
+	cache.Add("b", []byte("2"))
+	cache.Add("c", []byte("3"))
+	// touch a so b becomes the least recently used
+	cache.Get("a")
+	cache.Add("d", []byte("4"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected to find %s", key)
+		}
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 3 || stats.Evictions != 1 || stats.Hits != 4 || stats.Misses != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	// every entry is 1 byte key + 4 bytes value
+	cache := pokecache.NewPokeCache(time.Hour, pokecache.WithMaxBytes(10))
+	defer cache.Stop()
+
+	cache.Add("a", []byte("aaaa"))
+	cache.Add("b", []byte("bbbb"))
+	cache.Add("c", []byte("cccc"))
+
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("expected a to be evicted")
+	}
+	if stats := cache.Stats(); stats.Bytes != 10 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// replacing a value accounts for the size difference
+	cache.Add("c", []byte("c"))
+	if stats := cache.Stats(); stats.Bytes != 7 {
+		t.Errorf("unexpected bytes after replace: %+v", stats)
+	}
+
+	// an entry bigger than the whole budget is never stored
+	cache.Add("huge", []byte("0123456789"))
+	if _, ok := cache.Get("huge"); ok {
+		t.Errorf("expected oversized entry to be rejected")
+	}
+	if _, ok := cache.Get("b"); !ok {
+		t.Errorf("expected oversized entry to not evict others")
+	}
+}
+
+func TestLRUConcurrentGetAdd(t *testing.T) {
+	const maxEntries = 5
+	cache := pokecache.NewPokeCache(time.Hour, pokecache.WithMaxEntries(maxEntries))
+	defer cache.Stop()
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		cache.Add(key, []byte(key))
+	}
+
+	// touch everything but a from many goroutines at once
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := []string{"b", "c", "d", "e"}[i%4]
+			if _, ok := cache.Get(key); !ok {
+				t.Errorf("expected to find %s", key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cache.Add("f", []byte("f"))
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("expected a to be the least recently used and evicted")
+	}
+
+	// hammer the cache with adds and gets, bounds must hold at all times
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				cache.Add(key, []byte(key))
+				cache.Get(key)
+				if stats := cache.Stats(); stats.Entries > maxEntries {
+					t.Errorf("cache grew past its bound: %+v", stats)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	stats := cache.Stats()
+	if stats.Entries != maxEntries || stats.Evictions != 1+8*100 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestStaleEntries(t *testing.T) {
+	const ttl = 5 * time.Millisecond
+	cache := pokecache.NewPokeCache(ttl, pokecache.WithStaleTTL(time.Hour))
+	defer cache.Stop()
+
+	meta := pokecache.Metadata{ETag: `"abc"`, LastModified: "Mon, 02 Jan 2006 15:04:05 GMT"}
+	cache.AddEntry("https://example.com", []byte("testdata"), meta)
+
+	entry, ok := cache.GetEntry("https://example.com")
+	if !ok || entry.Stale || entry.Meta != meta {
+		t.Fatalf("expected fresh entry with metadata, got: %+v", entry)
+	}
+
+	time.Sleep(ttl + 10*time.Millisecond)
+
+	if _, ok := cache.Get("https://example.com"); ok {
+		t.Errorf("expected Get to ignore stale entries")
+	}
+	entry, ok = cache.GetEntry("https://example.com")
+	if !ok || !entry.Stale || string(entry.Val) != "testdata" || entry.Meta != meta {
+		t.Errorf("expected stale entry to be kept for revalidation, got: %+v", entry)
+	}
+
+	// refreshing a stale entry makes it fresh again
+	cache.AddEntry("https://example.com", entry.Val, entry.Meta)
+	if _, ok := cache.Get("https://example.com"); !ok {
+		t.Errorf("expected refreshed entry to be fresh")
+	}
+}
+
+func TestEntryHelpersOnPlainCache(t *testing.T) {
+	cache := pokecache.NewLayeredCache(plainCache{map[string][]byte{}}, plainCache{map[string][]byte{}})
+
+	pokecache.AddEntry(cache, "https://example.com", []byte("testdata"), pokecache.Metadata{ETag: `"abc"`})
+	entry, ok := pokecache.GetEntry(cache, "https://example.com")
+	if !ok || entry.Stale || string(entry.Val) != "testdata" {
+		t.Errorf("expected plain caches to return fresh entries, got: %+v", entry)
+	}
+}
+
+// plainCache only implements Cache, not MetaCache
+type plainCache struct {
+	store map[string][]byte
+}
+
+func (c plainCache) Get(key string) ([]byte, bool) {
+	val, ok := c.store[key]
+	return val, ok
+}
+
+func (c plainCache) Add(key string, val []byte) {
+	c.store[key] = val
+}
+
+func (c plainCache) Stop() {}
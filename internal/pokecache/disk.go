@@ -0,0 +1,206 @@
+package pokecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/fsutil"
+)
+
+// DefaultCacheDir returns the pokedex directory inside the user cache dir,
+// i.e: $XDG_CACHE_HOME/pokedexcli (or ~/.cache/pokedexcli) on linux
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pokedexcli"), nil
+}
+
+type diskEntry struct {
+	Key          string    `json:"key"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Val          []byte    `json:"val"`
+}
+
+// DiskCache keeps every entry in its own file so entries survive restarts
+// and a corrupted file only costs that single entry. Expired entries stay
+// available to GetEntry (for revalidation), those with an ETag or a
+// Last-Modified date even across restarts.
+type DiskCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int // checked on start, zero means unbounded
+	now      func() time.Time
+	mux      *sync.RWMutex
+}
+
+// DefaultMaxDiskBytes bounds the files of a DiskCache unless WithMaxBytes
+// says otherwise
+const DefaultMaxDiskBytes = 100 << 20
+
+type DiskOption interface {
+	applyDisk(cache *DiskCache)
+}
+
+func NewDiskCache(dir string, ttl time.Duration, opts ...DiskOption) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cache := &DiskCache{
+		dir:      dir,
+		ttl:      ttl,
+		maxBytes: DefaultMaxDiskBytes,
+		now:      time.Now,
+		mux:      &sync.RWMutex{},
+	}
+	for _, opt := range opts {
+		opt.applyDisk(cache)
+	}
+	cache.cleanCache(cache.now())
+	return cache, nil
+}
+
+func (c *DiskCache) Add(key string, val []byte) {
+	c.AddEntry(key, val, Metadata{})
+}
+
+func (c *DiskCache) AddEntry(key string, val []byte, meta Metadata) {
+	now := c.now()
+	data, err := json.Marshal(diskEntry{key, now, now.Add(c.ttl), meta.ETag, meta.LastModified, val})
+	if err != nil {
+		return
+	}
+	withWriteLock(c.mux, func() {
+		// the cache is best effort, a failed write is just a future miss
+		fsutil.WriteFileAtomic(c.path(key), data, 0o644)
+	})
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	entry, ok := c.GetEntry(key)
+	if !ok || entry.Stale {
+		return nil, false
+	}
+	return entry.GetVal(), true
+}
+
+func (c *DiskCache) GetEntry(key string) (PokeEntry, bool) {
+	r := withReadLock(c.mux, func() GetResult {
+		entry, err := readDiskEntry(c.path(key))
+		if err != nil || entry.Key != key {
+			return GetResult{}
+		}
+		return GetResult{PokeEntry{
+			CreatedAt: entry.CreatedAt,
+			Val:       entry.Val,
+			Meta:      Metadata{entry.ETag, entry.LastModified},
+			Stale:     !c.now().Before(entry.ExpiresAt),
+		}, true}
+	})
+	if r.ok {
+		return r.entry.(PokeEntry), true
+	}
+	return PokeEntry{}, false
+}
+
+// Stop is a no-op: entries are meant to outlive the session
+func (c *DiskCache) Stop() {}
+
+// sweptFile is a cache file kept by cleanCache
+type sweptFile struct {
+	path      string
+	size      int
+	createdAt time.Time
+}
+
+// cleanCache drops unreadable entries and the expired ones that cannot be
+// revalidated, then the oldest entries until the files fit in maxBytes
+func (c *DiskCache) cleanCache(t time.Time) {
+	withWriteLock(c.mux, func() {
+		var kept []sweptFile
+		size := 0
+		c.walk(func(path string) {
+			data, err := os.ReadFile(path)
+			var entry *diskEntry
+			if err == nil {
+				entry, err = parseDiskEntry(data)
+			}
+			// a leftover temp file is never read, whatever it holds
+			temp := strings.HasPrefix(filepath.Base(path), ".tmp-")
+			if err != nil || (!t.Before(entry.ExpiresAt) && (temp || !entry.hasValidators())) {
+				os.Remove(path)
+				return
+			}
+			kept = append(kept, sweptFile{path, len(data), entry.CreatedAt})
+			size += len(data)
+		})
+		if c.maxBytes <= 0 || size <= c.maxBytes {
+			return
+		}
+		slices.SortFunc(kept, func(a, b sweptFile) int {
+			return a.createdAt.Compare(b.createdAt)
+		})
+		for _, file := range kept {
+			if size <= c.maxBytes {
+				return
+			}
+			if os.Remove(file.path) == nil {
+				size -= file.size
+			}
+		}
+	})
+}
+
+func (c *DiskCache) walk(f func(path string)) error {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.Type().IsRegular() && (strings.HasSuffix(name, ".json") || strings.HasPrefix(name, ".tmp-")) {
+			f(filepath.Join(c.dir, name))
+		}
+	}
+	return nil
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (e *diskEntry) hasValidators() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+func readDiskEntry(path string) (*diskEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDiskEntry(data)
+}
+
+func parseDiskEntry(data []byte) (*diskEntry, error) {
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Key == "" {
+		return nil, errors.New("pokecache: entry without key")
+	}
+	return &entry, nil
+}
@@ -0,0 +1,182 @@
+package pokecache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/pokecache"
+)
+
+func TestDiskCacheAddGet(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Add("https://example.com", []byte("testdata"))
+
+	val, ok := cache.Get("https://example.com")
+	if !ok || string(val) != "testdata" {
+		t.Errorf("expected to find value, got: %s", val)
+	}
+	if _, ok := cache.Get("https://example.com/missing"); ok {
+		t.Errorf("expected to not find key")
+	}
+}
+
+func TestDiskCachePersistsAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	first, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Add("https://example.com", []byte("testdata"))
+	first.Stop()
+
+	second, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, ok := second.Get("https://example.com")
+	if !ok || string(val) != "testdata" {
+		t.Errorf("expected entry to survive a restart, got: %s", val)
+	}
+}
+
+func TestDiskCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := pokecache.NewDiskCache(dir, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Add("https://example.com", []byte("testdata"))
+
+	time.Sleep(10 * time.Millisecond)
+
+	if _, ok := cache.Get("https://example.com"); ok {
+		t.Errorf("expected entry to expire")
+	}
+
+	// expired entries are removed on the next start
+	if _, err := pokecache.NewDiskCache(dir, 5*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := os.ReadDir(dir)
+	if len(files) != 0 {
+		t.Errorf("expected expired entries to be removed, found: %d", len(files))
+	}
+}
+
+func TestDiskCacheKeepsRevalidableEntries(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	clock := pokecache.WithClock(func() time.Time { return now })
+	cache, err := pokecache.NewDiskCache(dir, time.Hour, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.AddEntry("validated", []byte("testdata"), pokecache.Metadata{ETag: `"v1"`})
+	cache.Add("plain", []byte("testdata"))
+
+	// on the next start only the expired entry without validators is removed
+	now = now.Add(2 * time.Hour)
+	cache, err = pokecache.NewDiskCache(dir, time.Hour, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := cache.GetEntry("validated"); !ok || !entry.Stale || entry.Meta.ETag != `"v1"` {
+		t.Errorf("expected a stale entry to revalidate, got: %+v, %v", entry, ok)
+	}
+	if _, ok := cache.GetEntry("plain"); ok {
+		t.Errorf("expected the expired entry without validators to be removed")
+	}
+
+	// over the budget the oldest entries go first, expired or not
+	for _, key := range []string{"second", "third"} {
+		now = now.Add(time.Minute)
+		cache.Add(key, []byte("testdata"))
+	}
+	files, _ := os.ReadDir(dir)
+	size := 0
+	for _, file := range files {
+		info, _ := file.Info()
+		size += int(info.Size())
+	}
+	cache, err = pokecache.NewDiskCache(dir, time.Hour, clock, pokecache.WithMaxBytes(size-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.GetEntry("validated"); ok {
+		t.Errorf("expected the oldest entry to be evicted")
+	}
+	for _, key := range []string{"second", "third"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+}
+
+func TestDiskCacheCorruptedFile(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Add("https://example.com/a", []byte("a"))
+	cache.Add("https://example.com/b", []byte("b"))
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 {
+		t.Fatalf("expected one file per entry, found: %d", len(files))
+	}
+	for _, f := range files {
+		os.WriteFile(filepath.Join(dir, f.Name()), []byte("{ not json"), 0o644)
+	}
+
+	if _, ok := cache.Get("https://example.com/a"); ok {
+		t.Errorf("expected corrupted entry to be a miss")
+	}
+
+	// the cache can still be opened and written after corruption
+	reopened, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.Add("https://example.com/a", []byte("fresh"))
+	val, ok := reopened.Get("https://example.com/a")
+	if !ok || string(val) != "fresh" {
+		t.Errorf("expected to overwrite corrupted entry, got: %s", val)
+	}
+}
+
+func TestLayeredCache(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := pokecache.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk.Add("https://example.com", []byte("testdata"))
+
+	memory := pokecache.NewPokeCache(time.Hour)
+	cache := pokecache.NewLayeredCache(memory, disk)
+
+	val, ok := cache.Get("https://example.com")
+	if !ok || string(val) != "testdata" {
+		t.Fatalf("expected to find value on disk, got: %s", val)
+	}
+	if _, ok := memory.Get("https://example.com"); !ok {
+		t.Errorf("expected disk hit to be promoted to memory")
+	}
+
+	cache.Add("https://example.com/path", []byte("moretestdata"))
+	if _, ok := disk.Get("https://example.com/path"); !ok {
+		t.Errorf("expected Add to write through to disk")
+	}
+
+	cache.Stop()
+	if _, ok := disk.Get("https://example.com/path"); !ok {
+		t.Errorf("expected disk entries to survive Stop")
+	}
+}
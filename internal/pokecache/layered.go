@@ -0,0 +1,56 @@
+package pokecache
+
+// LayeredCache checks a fast front cache (e.g: in-memory PokeCache) before
+// a slower back cache (e.g: DiskCache), promoting back hits to the front
+type LayeredCache struct {
+	front Cache
+	back  Cache
+}
+
+func NewLayeredCache(front, back Cache) *LayeredCache {
+	return &LayeredCache{front, back}
+}
+
+func (c *LayeredCache) Add(key string, val []byte) {
+	c.front.Add(key, val)
+	c.back.Add(key, val)
+}
+
+func (c *LayeredCache) AddEntry(key string, val []byte, meta Metadata) {
+	AddEntry(c.front, key, val, meta)
+	AddEntry(c.back, key, val, meta)
+}
+
+// GetEntry prefers a fresh entry from either layer over a stale one,
+// only fresh back entries are promoted so the front never turns stale data fresh
+func (c *LayeredCache) GetEntry(key string) (PokeEntry, bool) {
+	front, inFront := GetEntry(c.front, key)
+	if inFront && !front.Stale {
+		return front, true
+	}
+	back, inBack := GetEntry(c.back, key)
+	if inBack && !back.Stale {
+		AddEntry(c.front, key, back.Val, back.Meta)
+		return back, true
+	}
+	if inFront {
+		return front, true
+	}
+	return back, inBack
+}
+
+func (c *LayeredCache) Get(key string) ([]byte, bool) {
+	if val, ok := c.front.Get(key); ok {
+		return val, true
+	}
+	val, ok := c.back.Get(key)
+	if ok {
+		c.front.Add(key, val)
+	}
+	return val, ok
+}
+
+func (c *LayeredCache) Stop() {
+	c.front.Stop()
+	c.back.Stop()
+}
@@ -1,50 +1,170 @@
 package repl
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
 
 	"github.com/leobel/pokedexcli/internal/termscanner"
 )
 
+// ErrExit is returned by a command to end the session, it is not an error
+var ErrExit = errors.New("exit")
+
 type Repl struct {
 	Scanner termscanner.PokedexScanner
+	Out     io.Writer
+	running map[string]bool // scripts being run, to stop a script sourcing itself
+	script  *scriptInput    // the innermost script being run, nil for none
 }
 
 type CliCommand struct {
 	Name        string
 	Description string
-	Callback    func(...string) error
+	Callback    func(context.Context, ...string) error
+	Complete    func() []string // optional, candidates for the command argument
+	KeepCase    bool            // arguments are passed as typed instead of lowercased, i.e: file names
+}
+
+// ErrorPrinter is implemented by outputs printing errors their own way, i.e:
+// as JSON
+type ErrorPrinter interface {
+	PrintError(err error)
+}
+
+// completable is implemented by scanners supporting tab completion
+type completable interface {
+	SetCompleter(termscanner.Completer)
 }
 
-func NewRepl(scanner termscanner.PokedexScanner) *Repl {
-	return &Repl{scanner}
+// answerable is implemented by scanners that can read a line without
+// recording it in their history
+type answerable interface {
+	ScanAnswer() bool
 }
 
-func (r *Repl) Init(cmds map[string]CliCommand) {
+func NewRepl(scanner termscanner.PokedexScanner, out io.Writer) *Repl {
+	return &Repl{Scanner: scanner, Out: out}
+}
+
+// Init runs commands until one of them returns ErrExit or the input ends, in
+// which case the exit command is run. Failing commands are reported and the
+// session goes on, only an error reading the input is returned.
+func (r *Repl) Init(cmds map[string]CliCommand) error {
+	if scanner, ok := r.Scanner.(completable); ok {
+		scanner.SetCompleter(r.Completer(cmds))
+	}
 	for r.Scanner.Scan() {
 		text := r.Scanner.Text()
-		inputs := r.CleanInput(text)
-		if len(inputs) > 0 {
-			cmd := inputs[0]
-			cli, ok := cmds[cmd]
-			if ok {
-				if err := cli.Callback(inputs[1:]...); err != nil {
-					fmt.Println(err)
-					os.Exit(0)
-				}
-			} else {
-				fmt.Println("Unknown command")
-			}
-		}
-	}
-	if err := r.Scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading from input:", err)
-	}
-	err := cmds["exit"].Callback()
-	fmt.Println(err)
-	os.Exit(0)
+		cli, params, ok := r.parse(text, cmds)
+		if !ok {
+			continue
+		}
+		if cli.Callback == nil {
+			r.printError(unknownCommand(text))
+			continue
+		}
+		err := r.run(cli, params...)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrExit):
+			return nil
+		case errors.Is(err, context.Canceled):
+			r.printError(errors.New("command cancelled"))
+		default:
+			r.printError(err)
+		}
+	}
+	if exit, ok := cmds["exit"]; ok {
+		exit.Callback(context.Background())
+	}
+	return r.Scanner.Err()
+}
+
+// printError reports a failed command to the output
+func (r *Repl) printError(err error) {
+	if printer, ok := r.Out.(ErrorPrinter); ok {
+		printer.PrintError(err)
+		return
+	}
+	fmt.Fprintln(r.Out, err)
+}
+
+func unknownCommand(text string) error {
+	return fmt.Errorf("unknown command %q", strings.Fields(text)[0])
+}
+
+// run invokes the command with a context that is cancelled on Ctrl+C, so an
+// interrupt only aborts the in-flight command instead of the whole program
+func (r *Repl) run(cli CliCommand, params ...string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return cli.Callback(ctx, params...)
+}
+
+// Confirm asks a yes or no question, read from the next input line like a
+// command is, the next line of the script when one is running. Only y or yes
+// is a yes, the end of the input being a no. An empty question is not
+// printed, for callers printing it themselves. Answers are kept out of the
+// command history.
+func (r *Repl) Confirm(question string) bool {
+	if question != "" {
+		fmt.Fprintf(r.Out, "%s (yes/no)\n", question)
+	}
+	var answer string
+	if r.script != nil {
+		if !r.script.scan() {
+			return false
+		}
+		answer = r.script.scanner.Text()
+	} else {
+		scan := r.Scanner.Scan
+		if scanner, ok := r.Scanner.(answerable); ok {
+			scan = scanner.ScanAnswer
+		}
+		if !scan() {
+			return false
+		}
+		answer = r.Scanner.Text()
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// parse splits text into the command and its arguments. ok is false for a
+// blank line, and the command has no callback when it is unknown.
+func (r *Repl) parse(text string, cmds map[string]CliCommand) (cli CliCommand, params []string, ok bool) {
+	inputs := r.CleanInput(text)
+	if len(inputs) == 0 {
+		return CliCommand{}, nil, false
+	}
+	cli = cmds[inputs[0]]
+	params = inputs[1:]
+	if cli.KeepCase {
+		params = strings.Fields(text)[1:]
+	}
+	return cli, params, true
+}
+
+// Completer completes command names for the first word and delegates the
+// argument to the command's own Complete function
+func (r *Repl) Completer(cmds map[string]CliCommand) termscanner.Completer {
+	return func(words []string) []string {
+		if len(words) <= 1 {
+			return slices.Collect(maps.Keys(cmds))
+		}
+		cli, ok := cmds[strings.ToLower(words[0])]
+		if !ok || cli.Complete == nil || len(words) > 2 {
+			return nil
+		}
+		return cli.Complete()
+	}
 }
 
 func (r *Repl) CleanInput(text string) []string {
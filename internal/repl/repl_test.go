@@ -1,6 +1,12 @@
 package repl_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/leobel/pokedexcli/internal/repl"
@@ -29,7 +35,7 @@ func (c *MockScanner) Err() error {
 
 func TestCleanInput(t *testing.T) {
 	// arrange
-	replCli := repl.NewRepl(NewMockScanner())
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
 	cases := []struct {
 		input    string
 		expected []string
@@ -70,3 +76,159 @@ func TestCleanInput(t *testing.T) {
 		}
 	}
 }
+
+func TestCompleter(t *testing.T) {
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	cmds := map[string]repl.CliCommand{
+		"catch":   {Name: "catch", Complete: func() []string { return []string{"pikachu"} }},
+		"explore": {Name: "explore"},
+	}
+	completer := replCli.Completer(cmds)
+
+	cases := []struct {
+		words    []string
+		expected []string
+	}{
+		{words: []string{""}, expected: []string{"catch", "explore"}},
+		{words: []string{"ca"}, expected: []string{"catch", "explore"}},
+		{words: []string{"catch", "pi"}, expected: []string{"pikachu"}},
+		{words: []string{"CATCH", ""}, expected: []string{"pikachu"}},
+		{words: []string{"catch", "pikachu", ""}, expected: nil},
+		{words: []string{"explore", ""}, expected: nil},
+		{words: []string{"unknown", ""}, expected: nil},
+	}
+
+	for _, c := range cases {
+		actual := completer(c.words)
+		slices.Sort(actual)
+		if !slices.Equal(actual, c.expected) {
+			t.Errorf("Completer(%v) = %v; expected %v", c.words, actual, c.expected)
+		}
+	}
+}
+
+// linesScanner scans the given lines and then the error, if any
+type linesScanner struct {
+	lines []string
+	text  string
+	err   error
+}
+
+func (s *linesScanner) Scan() bool {
+	if len(s.lines) == 0 {
+		return false
+	}
+	s.text, s.lines = s.lines[0], s.lines[1:]
+	return true
+}
+
+func (s *linesScanner) Text() string { return s.text }
+func (s *linesScanner) Err() error   { return s.err }
+
+func TestInitKeepsGoingAfterErrors(t *testing.T) {
+	var ran []string
+	cmds := recordingCommands(&ran)
+	cmds["exit"] = repl.CliCommand{Name: "exit", Callback: func(context.Context, ...string) error {
+		ran = append(ran, "exit")
+		return repl.ErrExit
+	}}
+	scanner := &linesScanner{lines: []string{"catch pikachu", "", "fly", "catch bulbasaur", "exit", "map"}}
+
+	if err := repl.NewRepl(scanner, io.Discard).Init(cmds); err != nil {
+		t.Fatalf("Init() = %v; want nil", err)
+	}
+	expected := []string{"catch pikachu", "catch bulbasaur", "exit"}
+	if !slices.Equal(ran, expected) {
+		t.Errorf("ran %q; want %q", ran, expected)
+	}
+}
+
+// errorsOut records the errors it is given to print
+type errorsOut struct {
+	bytes.Buffer
+	errs []error
+}
+
+func (o *errorsOut) PrintError(err error) { o.errs = append(o.errs, err) }
+
+func TestInitPrintsErrors(t *testing.T) {
+	var ran []string
+	scanner := &linesScanner{lines: []string{"catch pikachu", "fly"}}
+
+	var text bytes.Buffer
+	repl.NewRepl(scanner, &text).Init(recordingCommands(&ran))
+	if want := "pikachu escaped\nunknown command \"fly\"\n"; text.String() != want {
+		t.Errorf("Init() printed %q; want %q", text.String(), want)
+	}
+
+	// an output printing errors its own way gets them all, nothing is written
+	var out errorsOut
+	scanner.lines = []string{"catch pikachu", "fly"}
+	repl.NewRepl(scanner, &out).Init(recordingCommands(&ran))
+	if len(out.errs) != 2 || !errors.Is(out.errs[0], errCatch) || out.Len() != 0 {
+		t.Errorf("PrintError got %v, %q was written; want both errors and nothing written", out.errs, out.String())
+	}
+}
+
+func TestInitRunsExitAtEndOfInput(t *testing.T) {
+	var ran []string
+	cmds := recordingCommands(&ran)
+	cmds["exit"] = repl.CliCommand{Name: "exit", Callback: func(context.Context, ...string) error {
+		ran = append(ran, "exit")
+		return repl.ErrExit
+	}}
+	readErr := errors.New("input closed")
+	scanner := &linesScanner{lines: []string{"map"}, err: readErr}
+
+	if err := repl.NewRepl(scanner, io.Discard).Init(cmds); !errors.Is(err, readErr) {
+		t.Errorf("Init() = %v; want %v", err, readErr)
+	}
+	if expected := []string{"map", "exit"}; !slices.Equal(ran, expected) {
+		t.Errorf("ran %q; want %q", ran, expected)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	scanner := &linesScanner{lines: []string{" YES ", "y", "nope", ""}}
+	r := repl.NewRepl(scanner, &out)
+	var answers []bool
+	for range 5 {
+		answers = append(answers, r.Confirm("Let it evolve?"))
+	}
+	// the end of the input is a no
+	if want := []bool{true, true, false, false, false}; !slices.Equal(answers, want) {
+		t.Errorf("Confirm() = %v; want %v", answers, want)
+	}
+	if !strings.HasPrefix(out.String(), "Let it evolve? (yes/no)\n") {
+		t.Errorf("Confirm() printed %q", out.String())
+	}
+
+	out.Reset()
+	scanner.lines = []string{"yes"}
+	if !r.Confirm("") || out.Len() != 0 {
+		t.Errorf("Confirm(\"\") printed %q; want the answer read without a question", out.String())
+	}
+}
+
+// answerScanner records which lines were read as answers
+type answerScanner struct {
+	linesScanner
+	answers []string
+}
+
+func (s *answerScanner) ScanAnswer() bool {
+	ok := s.Scan()
+	if ok {
+		s.answers = append(s.answers, s.text)
+	}
+	return ok
+}
+
+func TestConfirmScansAnswer(t *testing.T) {
+	scanner := &answerScanner{linesScanner: linesScanner{lines: []string{"yes"}}}
+	r := repl.NewRepl(scanner, io.Discard)
+	if !r.Confirm("Let it evolve?") || !slices.Equal(scanner.answers, []string{"yes"}) {
+		t.Errorf("answers = %q; want yes read with ScanAnswer", scanner.answers)
+	}
+}
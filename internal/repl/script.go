@@ -0,0 +1,117 @@
+package repl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScriptError is a failing line of a script
+type ScriptError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// scriptInput is the script being read, its line number counting the lines
+// read as answers too
+type scriptInput struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (in *scriptInput) scan() bool {
+	if !in.scanner.Scan() {
+		return false
+	}
+	in.line++
+	return true
+}
+
+// RunScript runs the commands in path one per line, skipping blank lines and
+// comments starting with #. It stops at the first failing line unless
+// continueOnError is set, in which case all of the errors are returned once
+// the script is done, for the caller to print. A command returning ErrExit
+// stops the script and ErrExit is returned as is. Questions asked by the
+// commands are answered by the next line of the script.
+func (r *Repl) RunScript(ctx context.Context, path string, cmds map[string]CliCommand, continueOnError bool) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if r.running[abs] {
+		return fmt.Errorf("%s: script is already running", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if r.running == nil {
+		r.running = map[string]bool{}
+	}
+	r.running[abs] = true
+	defer delete(r.running, abs)
+
+	in := &scriptInput{scanner: bufio.NewScanner(f)}
+	outer := r.script
+	r.script = in
+	defer func() { r.script = outer }()
+
+	var errs []error
+	for in.scan() {
+		n := in.line
+		text := strings.TrimSpace(in.scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		cli, params, _ := r.parse(text, cmds)
+		if cli.Callback == nil {
+			err = unknownCommand(text)
+		} else {
+			err = cli.Callback(ctx, params...)
+		}
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrExit) {
+			return err
+		}
+		err = &ScriptError{File: path, Line: n, Err: err}
+		if !continueOnError || ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	if err := in.scanner.Err(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// Source is the callback of the source command, running a script from inside
+// the session: source [--continue-on-error] <file>
+func (r *Repl) Source(cmds *map[string]CliCommand) func(context.Context, ...string) error {
+	return func(ctx context.Context, params ...string) error {
+		continueOnError := len(params) > 0 && params[0] == "--continue-on-error"
+		if continueOnError {
+			params = params[1:]
+		}
+		if len(params) != 1 {
+			return errors.New("usage: source [--continue-on-error] <file>")
+		}
+		return r.RunScript(ctx, params[0], *cmds, continueOnError)
+	}
+}
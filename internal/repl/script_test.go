@@ -0,0 +1,215 @@
+package repl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/leobel/pokedexcli/internal/repl"
+)
+
+var errCatch = errors.New("pikachu escaped")
+
+// recordingCommands records every command run, catch fails for pikachu
+func recordingCommands(ran *[]string) map[string]repl.CliCommand {
+	record := func(name string) func(context.Context, ...string) error {
+		return func(_ context.Context, params ...string) error {
+			*ran = append(*ran, strings.Join(append([]string{name}, params...), " "))
+			if name == "catch" && slices.Contains(params, "pikachu") {
+				return errCatch
+			}
+			return nil
+		}
+	}
+	return map[string]repl.CliCommand{
+		"map":     {Name: "map", Callback: record("map")},
+		"explore": {Name: "explore", Callback: record("explore")},
+		"catch":   {Name: "catch", Callback: record("catch")},
+	}
+}
+
+func writeScript(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const session = `# warm up
+map
+
+  # and catch a few
+explore Canalave-City-Area
+catch pikachu
+catch bulbasaur
+`
+
+func TestRunScriptStopsOnFirstError(t *testing.T) {
+	var ran []string
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	path := writeScript(t, "session.pdx", session)
+
+	err := replCli.RunScript(context.Background(), path, recordingCommands(&ran), false)
+
+	var scriptErr *repl.ScriptError
+	if !errors.As(err, &scriptErr) {
+		t.Fatalf("RunScript() = %v; want a *ScriptError", err)
+	}
+	if scriptErr.Line != 6 || !errors.Is(err, errCatch) {
+		t.Errorf("error = %v; want line 6 wrapping %v", err, errCatch)
+	}
+	if !strings.Contains(err.Error(), "session.pdx:6:") {
+		t.Errorf("error = %q; want the file and line number", err)
+	}
+	expected := []string{"map", "explore canalave-city-area", "catch pikachu"}
+	if !slices.Equal(ran, expected) {
+		t.Errorf("ran %q; want %q", ran, expected)
+	}
+}
+
+func TestRunScriptContinueOnError(t *testing.T) {
+	var ran []string
+	var out bytes.Buffer
+	replCli := repl.NewRepl(NewMockScanner(), &out)
+	path := writeScript(t, "session.pdx", session+"fly\n")
+
+	err := replCli.RunScript(context.Background(), path, recordingCommands(&ran), true)
+
+	if !errors.Is(err, errCatch) || !strings.Contains(err.Error(), `session.pdx:8: unknown command "fly"`) {
+		t.Errorf("RunScript() = %v; want every failing line", err)
+	}
+	// the errors are returned, printing them is left to the caller
+	if out.Len() != 0 {
+		t.Errorf("RunScript() printed %q; want nothing", out.String())
+	}
+	expected := []string{"map", "explore canalave-city-area", "catch pikachu", "catch bulbasaur"}
+	if !slices.Equal(ran, expected) {
+		t.Errorf("ran %q; want %q", ran, expected)
+	}
+}
+
+func TestRunScriptStopsWhenCancelled(t *testing.T) {
+	var ran []string
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmds := recordingCommands(&ran)
+	cmds["map"] = repl.CliCommand{Name: "map", Callback: func(ctx context.Context, _ ...string) error {
+		cancel()
+		return ctx.Err()
+	}}
+	path := writeScript(t, "session.pdx", session)
+
+	err := replCli.RunScript(ctx, path, cmds, true)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RunScript() = %v; want %v", err, context.Canceled)
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %q after cancelling; want nothing", ran)
+	}
+}
+
+func TestSource(t *testing.T) {
+	var ran []string
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	cmds := recordingCommands(&ran)
+	cmds["source"] = repl.CliCommand{Name: "source", Callback: replCli.Source(&cmds), KeepCase: true}
+
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "Inner.pdx")
+	os.WriteFile(inner, []byte("catch bulbasaur\n"), 0o644)
+	outer := writeScript(t, "outer.pdx", "map\nsource "+inner+"\n")
+
+	if err := replCli.RunScript(context.Background(), outer, cmds, false); err != nil {
+		t.Fatalf("RunScript() = %v", err)
+	}
+	expected := []string{"map", "catch bulbasaur"}
+	if !slices.Equal(ran, expected) {
+		t.Errorf("ran %q; want %q", ran, expected)
+	}
+
+	if err := cmds["source"].Callback(context.Background()); err == nil {
+		t.Error("source without a file = nil; want a usage error")
+	}
+}
+
+func TestSourceItself(t *testing.T) {
+	var ran []string
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	cmds := recordingCommands(&ran)
+	cmds["source"] = repl.CliCommand{Name: "source", Callback: replCli.Source(&cmds), KeepCase: true}
+
+	path := filepath.Join(t.TempDir(), "loop.pdx")
+	os.WriteFile(path, []byte("map\nsource "+path+"\n"), 0o644)
+
+	err := replCli.RunScript(context.Background(), path, cmds, false)
+	if err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("RunScript() = %v; want the loop to be refused", err)
+	}
+	if !slices.Equal(ran, []string{"map"}) {
+		t.Errorf("ran %q; want the script to run once", ran)
+	}
+}
+
+func TestRunScriptExit(t *testing.T) {
+	var ran []string
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	cmds := recordingCommands(&ran)
+	cmds["exit"] = repl.CliCommand{Name: "exit", Callback: func(context.Context, ...string) error { return repl.ErrExit }}
+	path := writeScript(t, "session.pdx", "map\nexit\ncatch bulbasaur\n")
+
+	err := replCli.RunScript(context.Background(), path, cmds, true)
+	if err != repl.ErrExit {
+		t.Errorf("RunScript() = %v; want %v as is", err, repl.ErrExit)
+	}
+	if !slices.Equal(ran, []string{"map"}) {
+		t.Errorf("ran %q; want the script to stop at exit", ran)
+	}
+}
+
+func TestRunScriptAnswersQuestions(t *testing.T) {
+	var answers []bool
+	replCli := repl.NewRepl(NewMockScanner(), io.Discard)
+	cmds := map[string]repl.CliCommand{
+		"ask": {Name: "ask", Callback: func(context.Context, ...string) error {
+			answers = append(answers, replCli.Confirm("Let it evolve?"))
+			return nil
+		}},
+	}
+	path := writeScript(t, "answers.pdx", "ask\nyes\nask\nno\nfly\n")
+
+	err := replCli.RunScript(context.Background(), path, cmds, false)
+
+	// answers are lines of the script, counted as such in errors
+	if !slices.Equal(answers, []bool{true, false}) {
+		t.Errorf("answers = %v; want [true false] read from the script", answers)
+	}
+	var scriptErr *repl.ScriptError
+	if !errors.As(err, &scriptErr) || scriptErr.Line != 5 {
+		t.Errorf("RunScript() = %v; want fly failing on line 5", err)
+	}
+}
+
+func TestSourceContinueOnErrorPrintsOnce(t *testing.T) {
+	var ran []string
+	var out bytes.Buffer
+	path := writeScript(t, "session.pdx", session)
+	scanner := &linesScanner{lines: []string{"source --continue-on-error " + path}}
+	replCli := repl.NewRepl(scanner, &out)
+	cmds := recordingCommands(&ran)
+	cmds["source"] = repl.CliCommand{Name: "source", Callback: replCli.Source(&cmds), KeepCase: true}
+
+	replCli.Init(cmds)
+
+	if n := strings.Count(out.String(), "pikachu escaped"); n != 1 {
+		t.Errorf("printed %q; want the failure once", out.String())
+	}
+}
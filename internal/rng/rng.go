@@ -0,0 +1,35 @@
+// Package rng is where the game draws its random outcomes from, catches and
+// encounters alike, so that a run can be replayed from its seed
+package rng
+
+import "math/rand/v2"
+
+// Rand is a seeded generator, reseeding it restarts the sequence for every
+// user of the embedded *rand.Rand
+type Rand struct {
+	*rand.Rand
+	pcg  *rand.PCG
+	seed uint64
+}
+
+func New(seed uint64) *Rand {
+	pcg := rand.NewPCG(seed, seed)
+	return &Rand{rand.New(pcg), pcg, seed}
+}
+
+// Seed is the seed the current sequence started from
+func (r *Rand) Seed() uint64 {
+	return r.seed
+}
+
+// Reseed restarts the sequence from seed
+func (r *Rand) Reseed(seed uint64) {
+	r.pcg.Seed(seed, seed)
+	r.seed = seed
+}
+
+// NewSeed returns a random seed. It is kept below 2^53 so it survives being
+// read as a JSON number by tools using floats, i.e: jq.
+func NewSeed() uint64 {
+	return rand.Uint64N(1 << 53)
+}
@@ -0,0 +1,37 @@
+package rng_test
+
+import (
+	"testing"
+
+	"github.com/leobel/pokedexcli/internal/rng"
+)
+
+func draw(r *rng.Rand) []uint64 {
+	return []uint64{r.Uint64(), r.Uint64(), r.Uint64()}
+}
+
+func TestSameSeedSameSequence(t *testing.T) {
+	a, b := rng.New(42), rng.New(42)
+	if x, y := draw(a), draw(b); x[0] != y[0] || x[1] != y[1] || x[2] != y[2] {
+		t.Errorf("seed 42 gave %v and %v", x, y)
+	}
+	if rng.New(43).Uint64() == rng.New(42).Uint64() {
+		t.Error("seeds 42 and 43 should not give the same sequence")
+	}
+}
+
+func TestReseed(t *testing.T) {
+	r := rng.New(1)
+	first := draw(r)
+	r.Reseed(7)
+	r.Reseed(1)
+	if again := draw(r); again[0] != first[0] || again[2] != first[2] {
+		t.Errorf("reseeding gave %v; want %v", again, first)
+	}
+	if r.Seed() != 1 {
+		t.Errorf("Seed() = %d; want 1", r.Seed())
+	}
+	if seed := rng.NewSeed(); seed >= 1<<53 {
+		t.Errorf("NewSeed() = %d; want below 2^53", seed)
+	}
+}
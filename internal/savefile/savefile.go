@@ -0,0 +1,285 @@
+package savefile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/fsutil"
+	"github.com/leobel/pokedexcli/internal/inventory"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+)
+
+// Version is the version of the save format written by this build
+const Version = 6
+
+// DefaultSlot is the slot autosaved on exit and loaded on start
+const DefaultSlot = "default"
+
+var ErrNoSave = errors.New("no save in slot")
+
+// Save is the content of a save file
+type Save struct {
+	Version int            `json:"version"`
+	SavedAt time.Time      `json:"saved_at"`
+	Seed    uint64         `json:"seed"` // of the session the save was made in
+	Pokemon []Pokemon      `json:"pokemon"`
+	Party   []int          `json:"party"` // UIDs of the party members, in order
+	Bag     map[string]int `json:"bag"`
+}
+
+// Pokemon is a caught Pokemon, the API data is kept so a save can be loaded
+// offline
+type Pokemon struct {
+	UID      int             `json:"uid"`
+	Nickname string          `json:"nickname,omitempty"`
+	CaughtAt time.Time       `json:"caught_at"`
+	Location string          `json:"location,omitempty"`
+	Level    int             `json:"level"`
+	Exp      int             `json:"exp"`
+	IVs      map[string]int  `json:"ivs"`
+	EVs      map[string]int  `json:"evs"`
+	Nature   Nature          `json:"nature"`
+	Data     pokeapi.Pokemon `json:"data"`
+}
+
+// Nature is kept with the stats it changes so stats can be computed offline
+type Nature struct {
+	Name      string `json:"name"`
+	Increased string `json:"increased,omitempty"`
+	Decreased string `json:"decreased,omitempty"`
+}
+
+// migrations upgrade a decoded save one version at a time, migrations[v]
+// turning a version v save into a version v+1 one. Any change to the format
+// must bump Version and add its migration here.
+var migrations = map[int]func(save map[string]any) error{
+	// version 2 adds the bag, players start over with a new one
+	1: func(save map[string]any) error {
+		bag := map[string]any{}
+		for name, count := range inventory.NewBag() {
+			bag[name] = count
+		}
+		save["bag"] = bag
+		return nil
+	},
+	// version 3 records the seed, older saves did not have one
+	2: func(save map[string]any) error {
+		save["seed"] = 0
+		return nil
+	},
+	// version 4 gives every Pokemon a UID, the first 6 making up the party
+	3: func(save map[string]any) error {
+		pokemon, _ := save["pokemon"].([]any)
+		party := []any{}
+		for i, p := range pokemon {
+			p, ok := p.(map[string]any)
+			if !ok {
+				return fmt.Errorf("pokemon %d is not an object", i)
+			}
+			p["uid"] = i + 1
+			if len(party) < 6 {
+				party = append(party, i+1)
+			}
+		}
+		save["party"] = party
+		return nil
+	},
+	// version 5 records levels, older Pokemon are level 5 and their experience
+	// is made up the first time they gain some
+	4: func(save map[string]any) error {
+		pokemon, _ := save["pokemon"].([]any)
+		for i, p := range pokemon {
+			p, ok := p.(map[string]any)
+			if !ok {
+				return fmt.Errorf("pokemon %d is not an object", i)
+			}
+			p["level"] = 5
+			p["exp"] = 0
+		}
+		return nil
+	},
+	// version 6 records IVs, EVs and natures. Older Pokemon get average IVs,
+	// no EVs and a neutral nature.
+	5: func(save map[string]any) error {
+		pokemon, _ := save["pokemon"].([]any)
+		for i, p := range pokemon {
+			p, ok := p.(map[string]any)
+			if !ok {
+				return fmt.Errorf("pokemon %d is not an object", i)
+			}
+			ivs := map[string]any{}
+			for _, stat := range []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"} {
+				ivs[stat] = 15
+			}
+			p["ivs"] = ivs
+			p["evs"] = map[string]any{}
+			p["nature"] = map[string]any{"name": "hardy"}
+		}
+		return nil
+	},
+}
+
+// DefaultDir returns $XDG_DATA_HOME/pokedexcli/saves, falling back to
+// ~/.local/share/pokedexcli/saves
+func DefaultDir() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "pokedexcli", "saves"), nil
+}
+
+// Store keeps every slot as <dir>/<slot>.json
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir}
+}
+
+var slotName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func (s *Store) path(slot string) (string, error) {
+	if !slotName.MatchString(slot) {
+		return "", fmt.Errorf("invalid slot %q, use letters, digits, - and _", slot)
+	}
+	return filepath.Join(s.dir, slot+".json"), nil
+}
+
+// Write saves to slot, replacing what it had. The version is set by Write.
+func (s *Store) Write(slot string, save Save) error {
+	path, err := s.path(slot)
+	if err != nil {
+		return err
+	}
+	pokemon := save.Pokemon
+	save.Version = Version
+	save.Pokemon = make([]Pokemon, 0, len(pokemon))
+	for _, p := range pokemon {
+		p.Data = trim(p.Data)
+		save.Pokemon = append(save.Pokemon, p)
+	}
+	data, err := json.Marshal(save)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, data, 0o644)
+}
+
+// Read loads slot, upgrading it to the current version. A missing slot is ErrNoSave.
+func (s *Store) Read(slot string) (*Save, error) {
+	path, err := s.path(slot)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w %s", ErrNoSave, slot)
+	}
+	if err != nil {
+		return nil, err
+	}
+	save, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("slot %s: %w", slot, err)
+	}
+	return save, nil
+}
+
+// SlotInfo describes a saved slot
+type SlotInfo struct {
+	Name    string
+	SavedAt time.Time
+	Pokemon int
+}
+
+// Slots lists the saved slots by name, unreadable ones are skipped
+func (s *Store) Slots() ([]SlotInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var slots []SlotInfo
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !slotName.MatchString(name) {
+			continue
+		}
+		save, err := s.Read(name)
+		if err != nil {
+			continue
+		}
+		slots = append(slots, SlotInfo{name, save.SavedAt, len(save.Pokemon)})
+	}
+	slices.SortFunc(slots, func(a, b SlotInfo) int { return strings.Compare(a.Name, b.Name) })
+	return slots, nil
+}
+
+func decode(data []byte) (*Save, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	version, ok := raw["version"].(float64)
+	if !ok {
+		return nil, errors.New("save has no version")
+	}
+	v := int(version)
+	if v > Version {
+		return nil, fmt.Errorf("save version %d is newer than the supported %d, update the Pokedex", v, Version)
+	}
+	for ; v < Version; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, fmt.Errorf("no migration from save version %d", v)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("migrating save version %d: %w", v, err)
+		}
+		raw["version"] = v + 1
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var save Save
+	if err := json.Unmarshal(data, &save); err != nil {
+		return nil, err
+	}
+	return &save, nil
+}
+
+// trim drops the bulky API data the Pokedex has no use for (sprites, cries,
+// game indices) and keeps only the latest way each move is learnt
+func trim(pokemon pokeapi.Pokemon) pokeapi.Pokemon {
+	pokemon.Sprites = pokeapi.Pokemon{}.Sprites
+	pokemon.Cries = pokeapi.Pokemon{}.Cries
+	pokemon.GameIndices = nil
+	pokemon.Moves = slices.Clone(pokemon.Moves)
+	for i, move := range pokemon.Moves {
+		if n := len(move.VersionGroupDetails); n > 1 {
+			pokemon.Moves[i].VersionGroupDetails = move.VersionGroupDetails[n-1:]
+		}
+	}
+	return pokemon
+}
@@ -0,0 +1,176 @@
+package savefile_test
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leobel/pokedexcli/internal/inventory"
+	"github.com/leobel/pokedexcli/internal/pokeapi"
+	"github.com/leobel/pokedexcli/internal/savefile"
+)
+
+func pikachu(t *testing.T) pokeapi.Pokemon {
+	t.Helper()
+	var p pokeapi.Pokemon
+	err := json.Unmarshal([]byte(`{"id":25,"name":"pikachu","base_experience":112,
+		"sprites":{"front_default":"url/25.png"},
+		"moves":[{"move":{"name":"thunder-shock"},"version_group_details":[
+			{"level_learned_at":1,"version_group":{"name":"red-blue"}},
+			{"level_learned_at":5,"version_group":{"name":"scarlet-violet"}}]}],
+		"stats":[{"base_stat":35,"stat":{"name":"hp"}}]}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestWriteRead(t *testing.T) {
+	store := savefile.NewStore(t.TempDir())
+	caughtAt := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	savedAt := caughtAt.Add(time.Hour)
+	pokemon := []savefile.Pokemon{{UID: 3, Nickname: "sparky", CaughtAt: caughtAt, Location: "viridian-forest-area", Level: 12, Exp: 1728,
+		IVs: map[string]int{"hp": 31, "speed": 4}, EVs: map[string]int{"speed": 2}, Nature: savefile.Nature{Name: "timid", Increased: "speed", Decreased: "attack"},
+		Data: pikachu(t)}}
+
+	bag := map[string]int{"poke-ball": 3, "razz-berry": 1}
+
+	if err := store.Write("default", savefile.Save{SavedAt: savedAt, Seed: 42, Pokemon: pokemon, Party: []int{3}, Bag: bag}); err != nil {
+		t.Fatal(err)
+	}
+	save, err := store.Read("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if save.Version != savefile.Version || !save.SavedAt.Equal(savedAt) || len(save.Pokemon) != 1 {
+		t.Fatalf("Read() = %+v; want version %d saved at %v with 1 Pokemon", save, savefile.Version, savedAt)
+	}
+	if save.Seed != 42 {
+		t.Errorf("Seed = %d; want 42", save.Seed)
+	}
+	if !maps.Equal(save.Bag, bag) {
+		t.Errorf("Bag = %v; want %v", save.Bag, bag)
+	}
+	p := save.Pokemon[0]
+	if p.UID != 3 || len(save.Party) != 1 || save.Party[0] != 3 {
+		t.Errorf("UID = %d, Party = %v; want #3 in the party", p.UID, save.Party)
+	}
+	if p.Nickname != "sparky" || !p.CaughtAt.Equal(caughtAt) || p.Location != "viridian-forest-area" {
+		t.Errorf("Pokemon = %q caught %v at %q; want sparky caught %v at viridian-forest-area", p.Nickname, p.CaughtAt, p.Location, caughtAt)
+	}
+	if p.Level != 12 || p.Exp != 1728 {
+		t.Errorf("Level = %d, Exp = %d; want level 12 with 1728 EXP", p.Level, p.Exp)
+	}
+	if p.IVs["hp"] != 31 || p.IVs["speed"] != 4 || p.EVs["speed"] != 2 || p.Nature != (savefile.Nature{Name: "timid", Increased: "speed", Decreased: "attack"}) {
+		t.Errorf("IVs = %v, EVs = %v, Nature = %+v; want them as saved", p.IVs, p.EVs, p.Nature)
+	}
+	if p.Data.ID != 25 || p.Data.Name != "pikachu" || len(p.Data.Stats) != 1 {
+		t.Errorf("Data = %+v; want pikachu's", p.Data)
+	}
+	// bulky data is left out, only the latest way to learn a move is kept
+	if p.Data.Sprites.FrontDefault != "" {
+		t.Error("sprites should not be saved")
+	}
+	details := p.Data.Moves[0].VersionGroupDetails
+	if len(details) != 1 || details[0].VersionGroup.Name != "scarlet-violet" {
+		t.Errorf("move details = %+v; want only scarlet-violet", details)
+	}
+}
+
+func TestReadMissingSlot(t *testing.T) {
+	store := savefile.NewStore(t.TempDir())
+	if _, err := store.Read("default"); !errors.Is(err, savefile.ErrNoSave) {
+		t.Errorf("Read() = %v; want %v", err, savefile.ErrNoSave)
+	}
+}
+
+func TestInvalidSlot(t *testing.T) {
+	store := savefile.NewStore(t.TempDir())
+	for _, slot := range []string{"", "../default", "my save"} {
+		if err := store.Write(slot, savefile.Save{SavedAt: time.Now()}); err == nil {
+			t.Errorf("Write(%q) should fail", slot)
+		}
+	}
+}
+
+func TestReadUnsupportedVersions(t *testing.T) {
+	dir := t.TempDir()
+	store := savefile.NewStore(dir)
+	cases := map[string]string{
+		"future":     `{"version":99,"pokemon":[]}`,
+		"no-version": `{"pokemon":[]}`,
+		"corrupt":    `{"version":`,
+	}
+	for slot, content := range cases {
+		os.WriteFile(filepath.Join(dir, slot+".json"), []byte(content), 0o644)
+		if _, err := store.Read(slot); err == nil || errors.Is(err, savefile.ErrNoSave) {
+			t.Errorf("Read(%s) = %v; want an error", slot, err)
+		}
+	}
+
+	_, err := store.Read("future")
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Read(future) = %v; want it to ask for an update", err)
+	}
+}
+
+func TestReadMigratesVersion1(t *testing.T) {
+	dir := t.TempDir()
+	store := savefile.NewStore(dir)
+	v1 := `{"version":1,"saved_at":"2024-05-01T10:30:00Z","pokemon":[
+		{"caught_at":"2024-05-01T10:00:00Z","data":{"id":25,"name":"pikachu"}},
+		{"caught_at":"2024-05-01T10:10:00Z","data":{"id":7,"name":"squirtle"}}]}`
+	os.WriteFile(filepath.Join(dir, "old.json"), []byte(v1), 0o644)
+
+	save, err := store.Read("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if save.Version != savefile.Version || len(save.Pokemon) != 2 || save.Pokemon[0].Data.Name != "pikachu" {
+		t.Errorf("Read() = %+v; want pikachu at version %d", save, savefile.Version)
+	}
+	if save.Pokemon[0].UID != 1 || save.Pokemon[1].UID != 2 || !slices.Equal(save.Party, []int{1, 2}) {
+		t.Errorf("UIDs %d and %d, Party = %v; want both numbered and in the party", save.Pokemon[0].UID, save.Pokemon[1].UID, save.Party)
+	}
+	if !maps.Equal(save.Bag, map[string]int(inventory.NewBag())) {
+		t.Errorf("Bag = %v; want a new bag", save.Bag)
+	}
+	if save.Seed != 0 {
+		t.Errorf("Seed = %d; want 0 for a save that did not record it", save.Seed)
+	}
+	if p := save.Pokemon[1]; p.Level != 5 || p.Exp != 0 {
+		t.Errorf("Level = %d, Exp = %d; want level 5 for a save that did not record it", p.Level, p.Exp)
+	}
+	if p := save.Pokemon[0]; len(p.IVs) != 6 || p.IVs["speed"] != 15 || len(p.EVs) != 0 || p.Nature != (savefile.Nature{Name: "hardy"}) {
+		t.Errorf("IVs = %v, EVs = %v, Nature = %+v; want average IVs, no EVs and a neutral nature", p.IVs, p.EVs, p.Nature)
+	}
+}
+
+func TestSlots(t *testing.T) {
+	dir := t.TempDir()
+	store := savefile.NewStore(dir)
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	store.Write("kanto", savefile.Save{SavedAt: now, Pokemon: []savefile.Pokemon{{Data: pikachu(t)}}})
+	store.Write("default", savefile.Save{SavedAt: now})
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hi"), 0o644)
+
+	slots, err := store.Slots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slots) != 2 || slots[0].Name != "default" || slots[1].Name != "kanto" || slots[1].Pokemon != 1 || !slots[1].SavedAt.Equal(now) {
+		t.Errorf("Slots() = %+v; want default and kanto with 1 Pokemon", slots)
+	}
+
+	if slots, err := savefile.NewStore(filepath.Join(dir, "missing")).Slots(); err != nil || len(slots) != 0 {
+		t.Errorf("Slots() = %v, %v; want none for a missing dir", slots, err)
+	}
+}
@@ -0,0 +1,93 @@
+package termscanner
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/leobel/pokedexcli/internal/fsutil"
+)
+
+// DefaultHistoryFile returns $XDG_STATE_HOME/pokedexcli/history,
+// falling back to ~/.local/state/pokedexcli/history
+func DefaultHistoryFile() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "pokedexcli", "history"), nil
+}
+
+// LoadHistory reads the history kept in path and saves every new command
+// back to it, keeping at most size entries. A missing file is not an error.
+func (ts *TermScanner) LoadHistory(path string, size int) error {
+	ts.historyFile = path
+	ts.historySize = size
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if cmd := scanner.Text(); cmd != "" {
+			history = appendHistory(history, cmd, size)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	ts.history = history
+	ts.index = len(ts.history)
+	return nil
+}
+
+// addHistory records cmd, commands starting with a space are kept out of it
+func (ts *TermScanner) addHistory(cmd string) {
+	if cmd == "" || strings.HasPrefix(cmd, " ") {
+		return
+	}
+	ts.history = appendHistory(ts.history, cmd, ts.historySize)
+	ts.index = len(ts.history)
+	if ts.historyFile != "" {
+		// history is best effort, failing to save must not break the prompt
+		saveHistory(ts.historyFile, ts.history)
+	}
+}
+
+// appendHistory moves cmd to the end, dropping older duplicates and the
+// oldest entries past size (zero means no limit)
+func appendHistory(history []string, cmd string, size int) []string {
+	history = slices.DeleteFunc(history, func(h string) bool { return h == cmd })
+	history = append(history, cmd)
+	if size > 0 && len(history) > size {
+		history = history[len(history)-size:]
+	}
+	return history
+}
+
+// saveHistory rewrites the history file atomically, readable by the user only
+func saveHistory(path string, history []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	var data strings.Builder
+	for _, cmd := range history {
+		data.WriteString(cmd)
+		data.WriteByte('\n')
+	}
+	return fsutil.WriteFileAtomic(path, []byte(data.String()), 0o600)
+}
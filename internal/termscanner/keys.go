@@ -0,0 +1,133 @@
+package termscanner
+
+import "unicode/utf8"
+
+type keyCode int
+
+const (
+	keyUnknown keyCode = iota
+	keyRune
+	keyEnter
+	keyInterrupt
+	keyEscape
+	keyBackspace
+	keyDelete
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyWordLeft
+	keyWordRight
+	keyKillToEnd
+	keyKillToStart
+	keyDeleteWord
+	keyTab
+	keySearch
+)
+
+type key struct {
+	code keyCode
+	r    rune // the typed rune, or the raw byte of a control key
+}
+
+// control characters, i.e: Ctrl+<letter>
+var controlKeys = map[byte]keyCode{
+	0x01: keyHome,        // Ctrl+A
+	0x02: keyLeft,        // Ctrl+B
+	0x03: keyInterrupt,   // Ctrl+C
+	0x04: keyDelete,      // Ctrl+D
+	0x05: keyEnd,         // Ctrl+E
+	0x06: keyRight,       // Ctrl+F
+	0x08: keyBackspace,   // Ctrl+H
+	0x09: keyTab,         // Tab
+	0x0a: keyEnter,       // \n
+	0x0b: keyKillToEnd,   // Ctrl+K
+	0x0d: keyEnter,       // \r
+	0x0e: keyDown,        // Ctrl+N
+	0x10: keyUp,          // Ctrl+P
+	0x12: keySearch,      // Ctrl+R
+	0x15: keyKillToStart, // Ctrl+U
+	0x17: keyDeleteWord,  // Ctrl+W
+	0x7f: keyBackspace,   // DEL, sent by the backspace key
+}
+
+// escape sequences without the leading ESC
+var escapeKeys = map[string]keyCode{
+	"[A":    keyUp,
+	"[B":    keyDown,
+	"[C":    keyRight,
+	"[D":    keyLeft,
+	"[H":    keyHome,
+	"[F":    keyEnd,
+	"OA":    keyUp,
+	"OB":    keyDown,
+	"OC":    keyRight,
+	"OD":    keyLeft,
+	"OH":    keyHome,
+	"OF":    keyEnd,
+	"[1~":   keyHome,
+	"[7~":   keyHome,
+	"[4~":   keyEnd,
+	"[8~":   keyEnd,
+	"[3~":   keyDelete,
+	"[1;5C": keyWordRight, // Ctrl+Right
+	"[1;5D": keyWordLeft,  // Ctrl+Left
+	"[1;3C": keyWordRight, // Alt+Right
+	"[1;3D": keyWordLeft,  // Alt+Left
+	"b":     keyWordLeft,  // Alt+B
+	"f":     keyWordRight, // Alt+F
+}
+
+// parseKey decodes the first key in b and returns how many bytes it used.
+// ok is false when b only holds the beginning of a key and more input is needed.
+// Terminals write a whole escape sequence at once, so an ESC at the very end of
+// the input is the Esc key itself.
+func parseKey(b []byte) (k key, n int, ok bool) {
+	if len(b) == 0 {
+		return key{}, 0, false
+	}
+	c := b[0]
+	switch {
+	case c == 0x1b:
+		return parseEscape(b)
+	case c < 0x20 || c == 0x7f:
+		return key{code: controlKeys[c], r: rune(c)}, 1, true
+	}
+	if !utf8.FullRune(b) {
+		return key{}, 0, false
+	}
+	r, size := utf8.DecodeRune(b)
+	return key{code: keyRune, r: r}, size, true
+}
+
+func parseEscape(b []byte) (key, int, bool) {
+	if len(b) == 1 {
+		return key{code: keyEscape}, 1, true
+	}
+	switch b[1] {
+	case '[':
+		// CSI: parameter bytes 0x30-0x3f, intermediate bytes 0x20-0x2f, final byte 0x40-0x7e
+		for i := 2; i < len(b); i++ {
+			if b[i] >= 0x40 && b[i] <= 0x7e {
+				return key{code: escapeKeys[string(b[1:i+1])]}, i + 1, true
+			}
+			if b[i] < 0x20 || b[i] > 0x3f {
+				// malformed, drop what we have so far
+				return key{code: keyUnknown}, i, true
+			}
+		}
+		return key{}, 0, false
+	case 'O':
+		if len(b) < 3 {
+			return key{}, 0, false
+		}
+		return key{code: escapeKeys[string(b[1:3])]}, 3, true
+	}
+	// Alt+<key> is sent as ESC <key>, a control character means Esc was pressed on its own
+	if b[1] >= 0x20 && b[1] < 0x7f {
+		return key{code: escapeKeys[string(b[1:2])]}, 2, true
+	}
+	return key{code: keyEscape}, 1, true
+}
@@ -0,0 +1,148 @@
+package termscanner
+
+import "unicode"
+
+// line is the editable input line, pos is the cursor position in runes
+type line struct {
+	buf []rune
+	pos int
+}
+
+func (l *line) String() string {
+	return string(l.buf)
+}
+
+func (l *line) set(s string) {
+	l.buf = []rune(s)
+	l.pos = len(l.buf)
+}
+
+func (l *line) reset() {
+	l.buf = l.buf[:0]
+	l.pos = 0
+}
+
+// atEnd reports whether the cursor is after the last rune
+func (l *line) atEnd() bool {
+	return l.pos == len(l.buf)
+}
+
+func (l *line) insert(r rune) {
+	l.buf = append(l.buf, 0)
+	copy(l.buf[l.pos+1:], l.buf[l.pos:])
+	l.buf[l.pos] = r
+	l.pos++
+}
+
+// backspace deletes the rune before the cursor
+func (l *line) backspace() bool {
+	if l.pos == 0 {
+		return false
+	}
+	l.buf = append(l.buf[:l.pos-1], l.buf[l.pos:]...)
+	l.pos--
+	return true
+}
+
+// delete deletes the rune under the cursor
+func (l *line) delete() bool {
+	if l.atEnd() {
+		return false
+	}
+	l.buf = append(l.buf[:l.pos], l.buf[l.pos+1:]...)
+	return true
+}
+
+func (l *line) left() bool {
+	if l.pos == 0 {
+		return false
+	}
+	l.pos--
+	return true
+}
+
+func (l *line) right() bool {
+	if l.atEnd() {
+		return false
+	}
+	l.pos++
+	return true
+}
+
+func (l *line) home() bool {
+	moved := l.pos != 0
+	l.pos = 0
+	return moved
+}
+
+func (l *line) end() bool {
+	moved := !l.atEnd()
+	l.pos = len(l.buf)
+	return moved
+}
+
+func (l *line) killToEnd() bool {
+	if l.atEnd() {
+		return false
+	}
+	l.buf = l.buf[:l.pos]
+	return true
+}
+
+func (l *line) killToStart() bool {
+	if l.pos == 0 {
+		return false
+	}
+	l.buf = append(l.buf[:0], l.buf[l.pos:]...)
+	l.pos = 0
+	return true
+}
+
+// deleteWord deletes the whitespace delimited word before the cursor (Ctrl+W)
+func (l *line) deleteWord() bool {
+	start := l.pos
+	for start > 0 && unicode.IsSpace(l.buf[start-1]) {
+		start--
+	}
+	for start > 0 && !unicode.IsSpace(l.buf[start-1]) {
+		start--
+	}
+	if start == l.pos {
+		return false
+	}
+	l.buf = append(l.buf[:start], l.buf[l.pos:]...)
+	l.pos = start
+	return true
+}
+
+// wordLeft moves to the start of the previous alphanumeric word (Alt+B)
+func (l *line) wordLeft() bool {
+	pos := l.pos
+	for pos > 0 && !isWordRune(l.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(l.buf[pos-1]) {
+		pos--
+	}
+	moved := pos != l.pos
+	l.pos = pos
+	return moved
+}
+
+// wordRight moves to the end of the next alphanumeric word (Alt+F)
+func (l *line) wordRight() bool {
+	pos := l.pos
+	for pos < len(l.buf) && !isWordRune(l.buf[pos]) {
+		pos++
+	}
+	for pos < len(l.buf) && isWordRune(l.buf[pos]) {
+		pos++
+	}
+	moved := pos != l.pos
+	l.pos = pos
+	return moved
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
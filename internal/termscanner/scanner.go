@@ -1,11 +1,14 @@
 package termscanner
 
 import (
-	"bytes"
+	"bufio"
+	"errors"
 	"fmt"
-	"math"
+	"io"
 	"os"
 	"slices"
+	"strings"
+	"unicode"
 
 	"golang.org/x/term"
 )
@@ -30,106 +33,278 @@ type PokedexScanner interface {
 	Err() error
 }
 
+// Completer returns the candidates for the word under completion. words holds
+// every word before the cursor, the last one being the (maybe empty) word typed
+// so far. Candidates not starting with that word are ignored.
+type Completer func(words []string) []string
+
 type TermScanner struct {
+	in      *os.File
+	out     io.Writer
 	fd      uintptr
 	prompt  string
 	cmd     string
+	line    line
+	pending []byte // read but not yet consumed input
 	history []string
 	index   int
 	term    Term
+	err     error
+
+	historyFile string
+	historySize int
+	answering   bool // the line being read is kept out of the history
+	search      search
+
+	completer Completer
+	listed    bool // tab was the last key and candidates may be listed
+
+	lines *bufio.Reader // set when the input is not a terminal
 }
 
-func New(prompt string, f *os.File, term Term) *TermScanner {
+// New reads commands from f and echoes them to out. When f is not a terminal
+// (a pipe or a file) lines are read as they come, without prompt, echo or
+// line editing.
+func New(prompt string, f *os.File, out io.Writer, term Term) *TermScanner {
 	ts := &TermScanner{
+		in:     f,
+		out:    out,
 		fd:     f.Fd(),
 		prompt: prompt,
 		index:  -1,
 		term:   term,
 	}
+	if !term.IsTerminal(int(ts.fd)) {
+		ts.lines = bufio.NewReader(f)
+	}
 	return ts
 }
 
+func (ts *TermScanner) SetCompleter(completer Completer) {
+	ts.completer = completer
+}
+
 func (ts *TermScanner) Scan() bool {
-	result := func() bool {
-		fmt.Print(ts.prompt)
-		var buf = bytes.Buffer{}
-		oldState, err := ts.term.MakeRaw(int(ts.fd))
+	if ts.lines != nil {
+		return ts.scanLine()
+	}
+	fmt.Fprint(ts.out, ts.prompt)
+	oldState, err := ts.term.MakeRaw(int(ts.fd))
+	if err != nil {
+		ts.err = err
+		fmt.Fprint(ts.out, "\n")
+		return false
+	}
+	defer ts.term.Restore(int(ts.fd), oldState)
+
+	ts.line.reset()
+	ts.search.active = false
+	for {
+		k, err := ts.readKey()
 		if err != nil {
-			panic(err)
+			if !errors.Is(err, io.EOF) {
+				ts.err = err
+			}
+			fmt.Fprint(ts.out, "\r\n")
+			return false
+		}
+		if ts.search.active && ts.searchKey(k) {
+			ts.listed = false
+			continue
 		}
-		defer ts.term.Restore(int(ts.fd), oldState)
-		for {
-			b := make([]byte, 3)
-			n, err := os.Stdin.Read(b)
-			if err != nil {
-				panic(err)
+
+		tabbed := k.code == keyTab
+		switch k.code {
+		case keyTab:
+			ts.complete()
+
+		case keySearch:
+			ts.startSearch()
+
+		case keyInterrupt:
+			ts.line.reset()
+			fmt.Fprint(ts.out, "\r\n")
+			return false
+
+		case keyEnter:
+			if k.r == '\r' && len(ts.pending) > 0 && ts.pending[0] == '\n' {
+				// \r\n is a single Enter
+				ts.pending = ts.pending[1:]
+			}
+			command := ts.line.String()
+			ts.cmd = command
+			if !ts.answering {
+				ts.addHistory(command)
+			}
+			ts.line.reset()
+			fmt.Fprint(ts.out, "\r\n")
+			return true
+
+		case keyUp:
+			if len(ts.history) == 0 {
+				continue
 			}
-			if b[0] == 0x03 { // Ctrl+C
-				buf.Reset()
-				fmt.Print("\r\n")
-				return false
+			if ts.index > 0 {
+				ts.index--
+				ts.line.set(ts.history[ts.index])
+				ts.redrawLine()
 			}
-			// get first 3 bytes
-			seq := b[:n]
-
-			switch {
-			case bytes.Equal(seq, []byte{0x1b, 0x5b, 0x41}): // ESC [ A
-				// Up arrow
-				if len(ts.history) == 0 {
-					continue
-				}
-				if ts.index > 0 {
-					ts.index--
-					buf.Reset()
-					buf.WriteString(ts.history[ts.index])
-					ts.redrawLine(&buf)
-				}
-
-			case bytes.Equal(seq, []byte{0x1b, 0x5b, 0x42}): // ESC [ B
-				// Down arrow
-				if len(ts.history) == 0 {
-					continue
-				}
-				if ts.index < len(ts.history)-1 {
-					ts.index++
-					buf.Reset()
-					buf.WriteString(ts.history[ts.index])
-					ts.redrawLine(&buf)
-				}
-
-			case slices.Index(seq, '\r') >= 0 || slices.Index(seq, '\n') >= 0:
-				index := int(math.Max(float64(slices.Index(seq, '\r')), float64(slices.Index(seq, '\n'))))
-				buf.Write(seq[0:index])
-				// Enter pressed
-				command := buf.String()
-				ts.cmd = command
-				if command != "" {
-					ts.cmd = command
-					if size := len(ts.history); size == 0 || ts.history[size-1] != command {
-						ts.history = append(ts.history, command)
-					}
-					ts.index = len(ts.history)
-				}
-				buf.Reset()
-				fmt.Print("\r\n")
-				return true
-
-			case seq[0] == 127:
-				// Backspace
-				if buf.Len() > 0 {
-					buf.Truncate(buf.Len() - 1)
-					ts.redrawLine(&buf)
-				}
-
-			default:
-				// print characters type
-				buf.Write(seq)
-				fmt.Printf("%s", seq)
+
+		case keyDown:
+			if len(ts.history) == 0 {
+				continue
+			}
+			if ts.index < len(ts.history)-1 {
+				ts.index++
+				ts.line.set(ts.history[ts.index])
+				ts.redrawLine()
+			}
+
+		case keyRune:
+			if ts.line.atEnd() {
+				// appending only needs to echo the character
+				ts.line.insert(k.r)
+				fmt.Fprint(ts.out, string(k.r))
+			} else {
+				ts.line.insert(k.r)
+				ts.redrawLine()
+			}
+
+		default:
+			if ts.edit(k.code) {
+				ts.redrawLine()
 			}
 		}
-	}()
+		if !tabbed {
+			ts.listed = false
+		}
+	}
+}
 
-	return result
+// ScanAnswer reads a line like Scan does without recording it in the
+// history, i.e: the answer to a question
+func (ts *TermScanner) ScanAnswer() bool {
+	ts.answering = true
+	defer func() { ts.answering = false }()
+	return ts.Scan()
+}
+
+// scanLine reads the next line of a non interactive input, the last line
+// doesn't need to end with a newline
+func (ts *TermScanner) scanLine() bool {
+	text, err := ts.lines.ReadString('\n')
+	if err != nil && (text == "" || !errors.Is(err, io.EOF)) {
+		if !errors.Is(err, io.EOF) {
+			ts.err = err
+		}
+		return false
+	}
+	ts.cmd = strings.TrimRight(text, "\r\n")
+	return true
+}
+
+// complete inserts the longest common prefix of the candidates, on a second
+// tab in a row that could not complete anything it lists them instead
+func (ts *TermScanner) complete() {
+	if ts.completer == nil {
+		return
+	}
+	before := string(ts.line.buf[:ts.line.pos])
+	words := strings.Fields(before)
+	if len(words) == 0 || unicode.IsSpace(ts.line.buf[ts.line.pos-1]) {
+		words = append(words, "")
+	}
+	word := words[len(words)-1]
+
+	var matches []string
+	for _, candidate := range ts.completer(words) {
+		if strings.HasPrefix(candidate, word) && !slices.Contains(matches, candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	completion := commonPrefix(matches)[len(word):]
+	if len(matches) == 1 {
+		completion += " "
+	}
+	if completion != "" {
+		for _, r := range completion {
+			ts.line.insert(r)
+		}
+		ts.redrawLine()
+		// still ambiguous, the next tab lists what is left
+		ts.listed = len(matches) > 1
+		return
+	}
+
+	if ts.listed {
+		slices.Sort(matches)
+		fmt.Fprintf(ts.out, "\r\n%s\r\n", strings.Join(matches, "  "))
+		ts.redrawLine()
+		ts.listed = false
+	} else {
+		ts.listed = true
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// edit applies a cursor movement or deletion, reporting whether the line changed
+func (ts *TermScanner) edit(code keyCode) bool {
+	switch code {
+	case keyBackspace:
+		return ts.line.backspace()
+	case keyDelete:
+		return ts.line.delete()
+	case keyLeft:
+		return ts.line.left()
+	case keyRight:
+		return ts.line.right()
+	case keyHome:
+		return ts.line.home()
+	case keyEnd:
+		return ts.line.end()
+	case keyWordLeft:
+		return ts.line.wordLeft()
+	case keyWordRight:
+		return ts.line.wordRight()
+	case keyKillToEnd:
+		return ts.line.killToEnd()
+	case keyKillToStart:
+		return ts.line.killToStart()
+	case keyDeleteWord:
+		return ts.line.deleteWord()
+	}
+	// unknown keys and sequences are ignored instead of echoed
+	return false
+}
+
+// readKey returns the next key press, reading more input only when the
+// pending bytes don't hold a complete key
+func (ts *TermScanner) readKey() (key, error) {
+	for {
+		if k, n, ok := parseKey(ts.pending); ok {
+			ts.pending = ts.pending[n:]
+			return k, nil
+		}
+		b := make([]byte, 64)
+		n, err := ts.in.Read(b)
+		if n == 0 && err != nil {
+			return key{}, err
+		}
+		ts.pending = append(ts.pending, b[:n]...)
+	}
 }
 
 func (ts *TermScanner) Text() string {
@@ -137,11 +312,15 @@ func (ts *TermScanner) Text() string {
 }
 
 func (ts *TermScanner) Err() error {
-	return nil
+	return ts.err
 }
 
-func (ts *TermScanner) redrawLine(buf *bytes.Buffer) {
+func (ts *TermScanner) redrawLine() {
 	// \r = return to line start, \x1b[2K = clear entire line
-	fmt.Printf("\r\x1b[2K%s", ts.prompt)
-	fmt.Print(buf.String())
+	fmt.Fprintf(ts.out, "\r\x1b[2K%s", ts.prompt)
+	fmt.Fprint(ts.out, ts.line.String())
+	// the cursor ends after the last character, move it back to its position
+	if back := len(ts.line.buf) - ts.line.pos; back > 0 {
+		fmt.Fprintf(ts.out, "\x1b[%dD", back)
+	}
 }
@@ -1,7 +1,13 @@
 package termscanner_test
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,31 +21,39 @@ func (fakeTerm) IsTerminal(fd int) bool                  { return true }
 func (fakeTerm) MakeRaw(fd int) (*term.State, error)     { return nil, nil }
 func (fakeTerm) Restore(fd int, state *term.State) error { return nil }
 
+// pipeTerm reports the input is not a terminal, it must never be made raw
+type pipeTerm struct{}
+
+func (pipeTerm) IsTerminal(fd int) bool { return false }
+func (pipeTerm) MakeRaw(fd int) (*term.State, error) {
+	panic("MakeRaw called on a non terminal input")
+}
+func (pipeTerm) Restore(fd int, state *term.State) error { return nil }
+
 type InputBuilder struct {
-	w        *os.File
-	r        *os.File
-	oldStdin *os.File
-	ts       *termscanner.TermScanner
+	w   *os.File
+	r   *os.File
+	out *bytes.Buffer
+	ts  *termscanner.TermScanner
 }
 
 func CreateInputBuilder() InputBuilder {
 	r, w, _ := os.Pipe()
-	oldStdin := os.Stdin
-	os.Stdin = r
+	out := &bytes.Buffer{}
 	return InputBuilder{
-		w:        w,
-		r:        r,
-		oldStdin: oldStdin,
-		ts:       termscanner.New("Pokedex > ", os.Stdin, fakeTerm{}),
+		w:   w,
+		r:   r,
+		out: out,
+		ts:  termscanner.New("Pokedex > ", r, out, fakeTerm{}),
 	}
 }
 
 func (c InputBuilder) Close() {
-	os.Stdin = c.oldStdin
 	c.w.Close()
+	c.r.Close()
 }
 
-// helper to feed input into os.Stdin for the duration of a Scan
+// helper to feed input into the scanner for the duration of a Scan
 func (c InputBuilder) withInput(input []byte, fn func(ts *termscanner.TermScanner)) InputBuilder {
 	c.w.Write(input)
 	fn(c.ts)
@@ -151,3 +165,356 @@ func TestTermScannerCtrlC(t *testing.T) {
 		t.Fatal("Scan() did not return after Ctrl+C")
 	}
 }
+
+func TestTermScannerLineEditing(t *testing.T) {
+	const (
+		left      = "\x1b[D"
+		right     = "\x1b[C"
+		home      = "\x1b[H"
+		end       = "\x1b[F"
+		homeTilde = "\x1b[1~"
+		endTilde  = "\x1b[4~"
+		del       = "\x1b[3~"
+		backspace = "\x7f"
+		ctrlA     = "\x01"
+		ctrlE     = "\x05"
+		ctrlK     = "\x0b"
+		ctrlU     = "\x15"
+		ctrlW     = "\x17"
+		altB      = "\x1bb"
+		altF      = "\x1bf"
+	)
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"insert in the middle", "ctch" + left + left + left + "a", "catch"},
+		{"left stops at start", "ab" + left + left + left + "x", "xab"},
+		{"right stops at end", "ab" + left + right + right + "c", "abc"},
+		{"home and end", "atch" + home + "c" + end + " pikachu", "catch pikachu"},
+		{"home and end tilde", "atch" + homeTilde + "c" + endTilde + "!", "catch!"},
+		{"ctrl a and ctrl e", "xplore" + ctrlA + "e" + ctrlE + " area", "explore area"},
+		{"backspace in the middle", "caatch" + left + left + left + backspace, "catch"},
+		{"backspace at start", "a" + home + backspace, "a"},
+		{"delete under cursor", "caatch" + home + right + del, "catch"},
+		{"delete at end", "catch" + del, "catch"},
+		{"kill to end", "catch pikachu" + home + altF + ctrlK, "catch"},
+		{"kill to start", "catch pikachu" + home + altF + ctrlU, " pikachu"},
+		{"delete word", "catch mr-mime" + ctrlW, "catch "},
+		{"delete word skips spaces", "catch pikachu  " + ctrlW, "catch "},
+		{"word left", "explore canalave-city-area" + altB + altB + "X", "explore canalave-Xcity-area"},
+		{"word right", "explore canalave-city" + home + altF + altF + "X", "explore canalaveX-city"},
+		{"ctrl left and right", "catch pikachu" + "\x1b[1;5D" + "X" + "\x1b[1;5C" + "!", "catch Xpikachu!"},
+		{"unknown sequences are ignored", "ca" + "\x1b[15~" + "\x1bx" + "\x1b[Z" + "tch", "catch"},
+		{"utf8", "pokmon" + left + left + left + "é", "pokémon"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			builder := CreateInputBuilder()
+			defer builder.Close()
+
+			builder.withInput([]byte(c.input+"\r"), func(ts *termscanner.TermScanner) {
+				if !ts.Scan() {
+					t.Fatal("expected Scan() to return true")
+				}
+				if actual := ts.Text(); actual != c.expected {
+					t.Errorf("Text() = %q; want %q", actual, c.expected)
+				}
+			})
+		})
+	}
+}
+
+func TestTermScannerSplitEscapeSequence(t *testing.T) {
+	builder := CreateInputBuilder()
+	defer builder.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if !builder.ts.Scan() {
+			t.Error("expected Scan() to return true")
+			return
+		}
+		if actual, expected := builder.ts.Text(), "catch"; actual != expected {
+			t.Errorf("Text() = %q; want %q", actual, expected)
+		}
+	}()
+
+	// the delete sequence arrives in two reads
+	builder.w.Write([]byte("caatch\x1b[H\x1b[C\x1b[3"))
+	time.Sleep(10 * time.Millisecond)
+	builder.w.Write([]byte("~\r"))
+
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Scan() did not return")
+	}
+}
+
+func TestTermScannerRedrawCursor(t *testing.T) {
+	builder := CreateInputBuilder()
+	defer builder.Close()
+
+	builder.withInput([]byte("ctch\x1b[D\x1b[D\x1b[Da\r"), func(ts *termscanner.TermScanner) {
+		ts.Scan()
+	})
+	out := builder.out.String()
+
+	// inserting "a" after "c" redraws the line and moves the cursor 3 columns back
+	expected := "\r\x1b[2KPokedex > catch\x1b[3D"
+	if !strings.Contains(out, expected) {
+		t.Errorf("redraw = %q; want it to contain %q", out, expected)
+	}
+}
+
+func TestTermScannerTabCompletion(t *testing.T) {
+	completer := func(words []string) []string {
+		if len(words) == 1 {
+			return []string{"catch", "exit", "explore", "evolution"}
+		}
+		if words[0] == "explore" {
+			return []string{"canalave-city-area", "canalave-city-gym", "eterna-city-area"}
+		}
+		return nil
+	}
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"unique command", "ca\t", "catch "},
+		{"common prefix", "ex\t", "ex"},
+		{"common prefix then more", "ex\tpl\t", "explore "},
+		{"argument", "explore et\t", "explore eterna-city-area "},
+		{"argument common prefix", "explore can\t", "explore canalave-city-"},
+		{"no candidates", "inspect pi\t", "inspect pi"},
+		{"empty line", "\t", ""},
+		{"completes before the cursor", "xplore" + "\x1b[H" + "expl\t", "explore xplore"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			builder := CreateInputBuilder()
+			defer builder.Close()
+			builder.ts.SetCompleter(completer)
+
+			builder.withInput([]byte(c.input+"\r"), func(ts *termscanner.TermScanner) {
+				if !ts.Scan() {
+					t.Fatal("expected Scan() to return true")
+				}
+				if actual := ts.Text(); actual != c.expected {
+					t.Errorf("Text() = %q; want %q", actual, c.expected)
+				}
+			})
+		})
+	}
+}
+
+func TestTermScannerTabTwiceListsCandidates(t *testing.T) {
+	builder := CreateInputBuilder()
+	defer builder.Close()
+	builder.ts.SetCompleter(func(words []string) []string {
+		return []string{"exit", "explore", "evolution"}
+	})
+
+	builder.withInput([]byte("e\t\t\r"), func(ts *termscanner.TermScanner) {
+		ts.Scan()
+	})
+	out := builder.out.String()
+
+	// candidates are listed below the prompt, then the line is redrawn
+	expected := "\r\nevolution  exit  explore\r\n\r\x1b[2KPokedex > e"
+	if !strings.Contains(out, expected) {
+		t.Errorf("output = %q; want it to contain %q", out, expected)
+	}
+}
+
+// scanLines feeds every line to the scanner, one Scan per line
+func scanLines(t *testing.T, builder InputBuilder, lines ...string) {
+	t.Helper()
+	for _, l := range lines {
+		builder.withInput([]byte(l+"\r"), func(ts *termscanner.TermScanner) {
+			if !ts.Scan() {
+				t.Fatalf("Scan() failed for %q", l)
+			}
+		})
+	}
+}
+
+func TestTermScannerPersistentHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pokedexcli", "history")
+
+	builder := CreateInputBuilder()
+	if err := builder.ts.LoadHistory(path, 3); err != nil {
+		t.Fatal(err)
+	}
+	scanLines(t, builder, "map", "explore canalave-city-area", "map", " catch secret", "catch pikachu", "help")
+	builder.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// duplicates moved to the end, space prefixed commands skipped, capped to 3
+	if actual, expected := string(data), "map\ncatch pikachu\nhelp\n"; actual != expected {
+		t.Errorf("history file = %q; want %q", actual, expected)
+	}
+
+	// a new session starts with the saved history
+	builder = CreateInputBuilder()
+	defer builder.Close()
+	if err := builder.ts.LoadHistory(path, 3); err != nil {
+		t.Fatal(err)
+	}
+	builder.withInput([]byte("\x1b[A\x1b[A\r"), func(ts *termscanner.TermScanner) {
+		if !ts.Scan() {
+			t.Fatal("Scan() failed")
+		}
+		if actual, expected := ts.Text(), "catch pikachu"; actual != expected {
+			t.Errorf("Text() = %q; want %q", actual, expected)
+		}
+	})
+}
+
+func TestTermScannerAnswersKeptOutOfHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	builder := CreateInputBuilder()
+	defer builder.Close()
+	if err := builder.ts.LoadHistory(path, 10); err != nil {
+		t.Fatal(err)
+	}
+	scanLines(t, builder, "catch pikachu")
+	builder.withInput([]byte("yes\r"), func(ts *termscanner.TermScanner) {
+		if !ts.ScanAnswer() || ts.Text() != "yes" {
+			t.Fatalf("ScanAnswer() read %q; want yes", ts.Text())
+		}
+	})
+	scanLines(t, builder, "map")
+
+	if data, _ := os.ReadFile(path); string(data) != "catch pikachu\nmap\n" {
+		t.Errorf("history file = %q; want the answer left out", data)
+	}
+	builder.withInput([]byte("\x1b[A\x1b[A\r"), func(ts *termscanner.TermScanner) {
+		if ts.Scan(); ts.Text() != "catch pikachu" {
+			t.Errorf("Text() = %q; want catch pikachu", ts.Text())
+		}
+	})
+}
+
+func TestTermScannerMissingHistoryFile(t *testing.T) {
+	builder := CreateInputBuilder()
+	defer builder.Close()
+	if err := builder.ts.LoadHistory(filepath.Join(t.TempDir(), "missing"), 10); err != nil {
+		t.Errorf("LoadHistory() = %v; want no error for a missing file", err)
+	}
+}
+
+func TestTermScannerReverseSearch(t *testing.T) {
+	const (
+		ctrlR = "\x12"
+		esc   = "\x1b"
+	)
+	history := []string{"catch pikachu", "explore canalave-city-area", "catch raichu", "map"}
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"most recent match", ctrlR + "catch", "catch raichu"},
+		{"repeated ctrl r goes older", ctrlR + "catch" + ctrlR, "catch pikachu"},
+		{"no older match keeps the last one", ctrlR + "catch" + ctrlR + ctrlR, "catch pikachu"},
+		{"narrowing the query", ctrlR + "c" + "a" + "n", "explore canalave-city-area"},
+		{"backspace widens the query", ctrlR + "catch p" + "\x7f\x7f", "catch raichu"},
+		{"no match", ctrlR + "zubat", ""},
+		{"failed search keeps the last match", ctrlR + "mewtwo", "map"},
+		{"esc restores the original line", "inspect" + ctrlR + "catch" + ctrlR + esc, "inspect"},
+		{"other keys accept the match", ctrlR + "raichu" + "\x1b[D\x1b[D" + "X", "catch raicXhu"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			builder := CreateInputBuilder()
+			defer builder.Close()
+			scanLines(t, builder, history...)
+
+			builder.withInput([]byte(c.input+"\r"), func(ts *termscanner.TermScanner) {
+				if !ts.Scan() {
+					t.Fatal("expected Scan() to return true")
+				}
+				if actual := ts.Text(); actual != c.expected {
+					t.Errorf("Text() = %q; want %q", actual, c.expected)
+				}
+			})
+		})
+	}
+}
+
+func TestTermScannerReverseSearchPrompt(t *testing.T) {
+	builder := CreateInputBuilder()
+	defer builder.Close()
+	scanLines(t, builder, "catch pikachu")
+
+	builder.withInput([]byte("\x12pika\x12x\r"), func(ts *termscanner.TermScanner) {
+		ts.Scan()
+	})
+	out := builder.out.String()
+	for _, expected := range []string{
+		"(reverse-i-search)`pika': catch pikachu",
+		"(failed reverse-i-search)`pikax': catch pikachu",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("output = %q; want it to contain %q", out, expected)
+		}
+	}
+}
+
+func TestTermScannerNonTerminal(t *testing.T) {
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	w.WriteString("map\r\n\nexplore canalave-city-area\ncatch pikachu")
+	w.Close()
+
+	var out bytes.Buffer
+	ts := termscanner.New("Pokedex > ", r, &out, pipeTerm{})
+	var lines []string
+	for ts.Scan() {
+		lines = append(lines, ts.Text())
+	}
+
+	expected := []string{"map", "", "explore canalave-city-area", "catch pikachu"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("lines = %q; want %q", lines, expected)
+	}
+	if out.Len() != 0 {
+		t.Errorf("output = %q; want no prompt nor echo", out.String())
+	}
+	if err := ts.Err(); err != nil {
+		t.Errorf("Err() = %v; want nil at end of input", err)
+	}
+}
+
+func TestTermScannerMakeRawError(t *testing.T) {
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	defer w.Close()
+
+	ts := termscanner.New("Pokedex > ", r, io.Discard, failingTerm{})
+	if ts.Scan() {
+		t.Error("Scan() = true; want false when the terminal can't be set up")
+	}
+	if ts.Err() == nil {
+		t.Error("Err() = nil; want the MakeRaw error")
+	}
+}
+
+type failingTerm struct{}
+
+func (failingTerm) IsTerminal(fd int) bool { return true }
+func (failingTerm) MakeRaw(fd int) (*term.State, error) {
+	return nil, errors.New("not supported")
+}
+func (failingTerm) Restore(fd int, state *term.State) error { return nil }
@@ -0,0 +1,80 @@
+package termscanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// search is the state of a Ctrl+R reverse incremental search
+type search struct {
+	active   bool
+	query    []rune
+	index    int    // history index of the current match, len(history) if none
+	original string // line to restore on Esc
+	failed   bool
+}
+
+func (ts *TermScanner) startSearch() {
+	ts.search = search{
+		active:   true,
+		index:    len(ts.history),
+		original: ts.line.String(),
+	}
+	ts.redrawSearch()
+}
+
+// searchKey handles a key while searching, reporting whether it was consumed.
+// Keys that are not consumed end the search keeping the match on the line,
+// and must then be handled as usual.
+func (ts *TermScanner) searchKey(k key) bool {
+	s := &ts.search
+	switch k.code {
+	case keySearch:
+		// look for an older match of the same query
+		ts.findMatch(s.index - 1)
+	case keyRune:
+		s.query = append(s.query, k.r)
+		ts.findMatch(s.index)
+	case keyBackspace:
+		if len(s.query) > 0 {
+			s.query = s.query[:len(s.query)-1]
+		}
+		ts.findMatch(len(ts.history) - 1)
+	case keyEscape:
+		ts.line.set(s.original)
+		s.active = false
+		ts.redrawLine()
+	default:
+		s.active = false
+		ts.redrawLine()
+		return false
+	}
+	if s.active {
+		ts.redrawSearch()
+	}
+	return true
+}
+
+// findMatch looks for the query from history index from backwards,
+// on a match the line is set to it, otherwise the previous match is kept
+func (ts *TermScanner) findMatch(from int) {
+	s := &ts.search
+	query := string(s.query)
+	for i := min(from, len(ts.history)-1); i >= 0; i-- {
+		if strings.Contains(ts.history[i], query) {
+			s.index = i
+			s.failed = false
+			ts.line.set(ts.history[i])
+			return
+		}
+	}
+	s.failed = true
+}
+
+func (ts *TermScanner) redrawSearch() {
+	label := "reverse-i-search"
+	if ts.search.failed {
+		label = "failed " + label
+	}
+	fmt.Fprintf(ts.out, "\r\x1b[2K(%s)`%s': %s", label, string(ts.search.query), ts.line.String())
+}
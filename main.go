@@ -1,27 +1,69 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
 	"time"
 
 	"github.com/leobel/pokedexcli/internal/commands"
 	"github.com/leobel/pokedexcli/internal/pokeapi"
 	"github.com/leobel/pokedexcli/internal/pokecache"
 	"github.com/leobel/pokedexcli/internal/repl"
+	"github.com/leobel/pokedexcli/internal/rng"
+	"github.com/leobel/pokedexcli/internal/savefile"
 	"github.com/leobel/pokedexcli/internal/termscanner"
 )
 
 var supportedCommands map[string]repl.CliCommand
 
 func main() {
-	cache := pokecache.NewPokeCache(10 * time.Second)
-	api := pokeapi.NewPokeApi("https://pokeapi.co/api/v2", cache)
+	output := flag.String("output", "text", "output format, text or json (one JSON object per line)")
+	seedFlag := flag.String("seed", "", "seed of the random outcomes, to replay a run (random if not given)")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: pokedexcli [--output text|json] [--seed n] [run [--continue-on-error] <file>]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	format, err := commands.ParseFormat(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	out := commands.NewOutput(os.Stdout, format)
+	seed := rng.NewSeed()
+	if *seedFlag != "" {
+		if seed, err = commands.ParseSeed(*seedFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	random := rng.New(seed)
 
-	helpCmd := commands.NewCommandHelp(&supportedCommands)
-	exitCmd := commands.NewCommandExit(api.Cache)
-	mapCmd := commands.NewCommandMap[pokecache.Cache](api)
-	pokedexCmd := commands.NewCommandPokedex[pokecache.Cache](api)
+	cache := newCache()
+	api := pokeapi.NewPokeApi("https://pokeapi.co/api/v2", cache, pokeapi.WithRateLimit(10, 20), pokeapi.WithStaleWhileRevalidate())
+
+	helpCmd := commands.NewCommandHelp(&supportedCommands, out)
+	mapCmd := commands.NewCommandMap[pokecache.Cache](api, out)
+	travelCmd := commands.NewCommandTravel[pokecache.Cache](api, random.Rand, out)
+	scanner := termscanner.New("Pokedex > ", os.Stdin, os.Stdout, termscanner.RealTerm{})
+	cliRepl := repl.NewRepl(scanner, out)
+	pokedexCmd := commands.NewCommandPokedex[pokecache.Cache](api, out,
+		commands.WithArea[pokecache.Cache](travelCmd.CurrentArea),
+		commands.WithPokemonCatcher[pokecache.Cache](commands.PokedexPokemonCatcher{Rand: random.Rand}),
+		commands.WithRand[pokecache.Cache](random.Rand),
+		commands.WithConfirm[pokecache.Cache](cliRepl.Confirm))
+	saveCmd, autosave := loadPokedex(pokedexCmd, random, out)
+	exitCmd := commands.NewCommandExit(api.Cache, out, autosave...)
+	evolutionCmd := commands.NewCommandEvolution[pokecache.Cache](api, out)
+	formatCmd := commands.NewCommandFormat(out)
+	seedCmd := commands.NewCommandSeed(random, out)
+	typesCmd := commands.NewCommandTypes[pokecache.Cache](api, out)
+	battleCmd := commands.NewCommandBattle[pokecache.Cache](api, pokedexCmd, typesCmd.TypeChart, random.Rand, out)
 
 	supportedCommands = map[string]repl.CliCommand{
 		"exit": {
@@ -48,28 +90,228 @@ func main() {
 			Name:        "explore",
 			Description: "List of all the Pokemons located in a specific area",
 			Callback:    mapCmd.ExploreArea,
+			Complete:    mapCmd.AreaNames,
 		},
 		"catch": {
 			Name:        "catch",
-			Description: "Trying to catch a Pokemon by name",
+			Description: "Throw a ball (poke-ball if none is given) at a Pokemon living in the area you are in",
 			Callback:    pokedexCmd.CatchPokemon,
+			Complete:    travelCmd.PokemonNames,
+		},
+		"bag": {
+			Name:        "bag",
+			Description: "Show the balls and berries you carry",
+			Callback:    pokedexCmd.ShowBag,
+		},
+		"use": {
+			Name:        "use",
+			Description: "Use an item of your bag, a berry makes the next catch easier",
+			Callback:    pokedexCmd.UseItem,
+			Complete:    pokedexCmd.ItemNames,
+		},
+		"travel": {
+			Name:        "travel",
+			Description: "Travel to a location area to catch the Pokemon living there",
+			Callback:    travelCmd.Travel,
+			Complete:    mapCmd.AreaNames,
+		},
+		"encounter": {
+			Name:        "encounter",
+			Description: "Look for a wild Pokemon in the area you are in, by walk or the given method",
+			Callback:    travelCmd.Encounter,
+			Complete:    travelCmd.Methods,
 		},
 		"inspect": {
 			Name:        "inspect",
-			Description: "Show name, height, weight, stats and type(s) of Pokemon",
+			Description: "Show name, level, height, weight, stats and type(s) of a caught Pokemon, by #id, nickname or name",
 			Callback:    pokedexCmd.InspectPokemon,
+			Complete:    pokedexCmd.PokemonNames,
+		},
+		"nickname": {
+			Name:        "nickname",
+			Description: "Give a caught Pokemon a nickname, or clear it",
+			Callback:    pokedexCmd.SetNickname,
+			Complete:    pokedexCmd.PokemonNames,
+			KeepCase:    true,
+		},
+		"party": {
+			Name:        "party",
+			Description: "Show your party of up to 6 Pokemon, or change it: party add|remove|lead <pokemon>, party swap <pokemon> <pokemon>",
+			Callback:    pokedexCmd.ShowParty,
+			Complete:    pokedexCmd.PartySubcommands,
+		},
+		"box": {
+			Name:        "box",
+			Description: "Show the Pokemon you caught that are not in your party",
+			Callback:    pokedexCmd.ShowBox,
 		},
 		"pokedex": {
 			Name:        "pokedex",
 			Description: "Show all Pokemon you've caught so far",
 			Callback:    pokedexCmd.ShowPokemons,
 		},
+		"evolution": {
+			Name:        "evolution",
+			Description: "Show the evolution chain of a Pokemon with its triggers",
+			Callback:    evolutionCmd.ShowEvolution,
+			Complete:    func() []string { return slices.Concat(mapCmd.PokemonNames(), pokedexCmd.PokemonNames()) },
+		},
+		"battle": {
+			Name:        "battle",
+			Description: "Battle your lead, or another party member, against a wild Pokemon of any species: battle [mine] <opponent>",
+			Callback:    battleCmd.Start,
+			Complete:    pokedexCmd.PokemonNames,
+		},
+		"attack": {
+			Name:        "attack",
+			Description: "Use a move of your Pokemon for the next turn of the battle, winning gives it experience",
+			Callback:    battleCmd.Attack,
+			Complete:    battleCmd.MoveNames,
+		},
+		"flee": {
+			Name:        "flee",
+			Description: "Leave the battle",
+			Callback:    battleCmd.Flee,
+		},
+		"weakness": {
+			Name:        "weakness",
+			Description: "Show which types are super effective, not very effective or have no effect against a Pokemon",
+			Callback:    typesCmd.Weakness,
+			Complete:    func() []string { return slices.Concat(mapCmd.PokemonNames(), pokedexCmd.PokemonNames()) },
+		},
+		"matchup": {
+			Name:        "matchup",
+			Description: "Show how effective a type is against a Pokemon or a type: matchup <attacker-type> <defender>",
+			Callback:    typesCmd.Matchup,
+			Complete:    typesCmd.TypeNames,
+		},
+		"format": {
+			Name:        "format",
+			Description: "Show or set the output format: text or json",
+			Callback:    formatCmd.SetFormat,
+			Complete:    formatCmd.Formats,
+		},
+		"seed": {
+			Name:        "seed",
+			Description: "Show the seed of the random outcomes, or restart them from a new one to replay a run",
+			Callback:    seedCmd.SetSeed,
+		},
+		"source": {
+			Name:        "source",
+			Description: "Run the commands in a script file, use --continue-on-error to run it all",
+			Callback:    cliRepl.Source(&supportedCommands),
+			KeepCase:    true,
+		},
+	}
+	if saveCmd != nil {
+		saveCmd.OnLoad = append(saveCmd.OnLoad, battleCmd.End)
+		supportedCommands["save"] = repl.CliCommand{
+			Name:        "save",
+			Description: "Save your Pokedex to a slot (default if none is given), it is also saved on exit",
+			Callback:    saveCmd.Save,
+			Complete:    saveCmd.SlotNames,
+		}
+		supportedCommands["load"] = repl.CliCommand{
+			Name:        "load",
+			Description: "Replace your Pokedex with the one saved in a slot (default if none is given)",
+			Callback:    saveCmd.Load,
+			Complete:    saveCmd.SlotNames,
+		}
+		supportedCommands["slots"] = repl.CliCommand{
+			Name:        "slots",
+			Description: "List the saved slots",
+			Callback:    saveCmd.Slots,
+		}
 	}
 
-	scanner := termscanner.New("Pokedex > ", os.Stdin, termscanner.RealTerm{})
+	if args := flag.Args(); len(args) > 0 && args[0] == "run" {
+		runScript(cliRepl, random, args[1:])
+		return
+	}
+
+	if historyFile, err := termscanner.DefaultHistoryFile(); err == nil {
+		if err := scanner.LoadHistory(historyFile, 1000); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not load history:", err)
+		}
+	}
 
-	cliRepl := repl.NewRepl(scanner)
+	// a seed given with --seed is known already, a generated one is needed to replay the run
+	if *seedFlag == "" {
+		fmt.Fprintf(os.Stderr, "Seed %d, start with --seed %d to replay this run\n", random.Seed(), random.Seed())
+	}
 
 	// init REPL cli
-	cliRepl.Init(supportedCommands)
+	if err := cliRepl.Init(supportedCommands); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading from input:", err)
+		os.Exit(1)
+	}
+}
+
+// runScript implements `pokedexcli run [--continue-on-error] <file>`
+func runScript(cliRepl *repl.Repl, random *rng.Rand, args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	continueOnError := flags.Bool("continue-on-error", false, "keep running after a failing command")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: pokedexcli run [--continue-on-error] <file>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// stdout is for the results, the seed goes to stderr along with the errors
+	fmt.Fprintf(os.Stderr, "Running %s with seed %d\n", flags.Arg(0), random.Seed())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := cliRepl.RunScript(ctx, flags.Arg(0), supportedCommands, *continueOnError)
+	stop()
+	if errors.Is(err, repl.ErrExit) {
+		// the script already ran exit
+		return
+	}
+	supportedCommands["exit"].Callback(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// loadPokedex restores the Pokedex saved in the default slot and returns the
+// autosave to run on exit. Saves are disabled when there is nowhere to keep
+// them, and autosave is left out when the save can't be read so it is not
+// overwritten.
+func loadPokedex(pokedexCmd *commands.CommandPokedex[pokecache.Cache], random *rng.Rand, out *commands.Output) (*commands.CommandSave[pokecache.Cache], []func() error) {
+	dir, err := savefile.DefaultDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Saves disabled:", err)
+		return nil, nil
+	}
+	saveCmd := commands.NewCommandSave(pokedexCmd, savefile.NewStore(dir), random, out)
+	if _, err := saveCmd.LoadSlot(savefile.DefaultSlot); err != nil && !errors.Is(err, savefile.ErrNoSave) {
+		fmt.Fprintln(os.Stderr, "Could not load your Pokedex, autosave is off:", err)
+		return saveCmd, nil
+	}
+	autosave := func() error {
+		_, err := saveCmd.SaveSlot(savefile.DefaultSlot)
+		return err
+	}
+	return saveCmd, []func() error{autosave}
+}
+
+// newCache puts the in-memory cache in front of the on-disk one, falling back
+// to memory only if the cache directory is not usable
+func newCache() pokecache.Cache {
+	memory := pokecache.NewPokeCache(10*time.Second, pokecache.WithMaxEntries(500), pokecache.WithMaxBytes(64<<20), pokecache.WithStaleTTL(10*time.Minute))
+	dir, err := pokecache.DefaultCacheDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Disk cache disabled:", err)
+		return memory
+	}
+	disk, err := pokecache.NewDiskCache(dir, 24*time.Hour)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Disk cache disabled:", err)
+		return memory
+	}
+	return pokecache.NewLayeredCache(memory, disk)
 }